@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/archive"
+	"github.com/spf13/cobra"
+)
+
+var archiveOutputDir string
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Inspect and import the download archive",
+	Long: `Every download is recorded in an archive inside the output directory
+(.yeetrap/archive.json) with its files, format, size and checksums. Archived
+videos are skipped by 'yeetrap download' unless --force is given.`,
+}
+
+var archiveListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List archived videos",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		downloadArchive, err := openOutputArchive()
+		if err != nil {
+			return err
+		}
+
+		entries := downloadArchive.Entries()
+		fmt.Printf("📦 %d archived videos in %s\n\n", len(entries), downloadArchive.Location())
+		for _, entry := range entries {
+			title := entry.Title
+			if title == "" {
+				title = "(imported from " + entry.Source + ")"
+			}
+			fmt.Printf("%s  %s  %s\n", entry.ID, entry.DownloadedAt.Local().Format("2006-01-02 15:04"), title)
+			if len(entry.Files) > 0 {
+				fmt.Printf("   %d file(s), %.1f MB, format: %s\n", len(entry.Files), float64(entry.Size)/(1024*1024), entry.Format)
+			}
+		}
+		return nil
+	},
+}
+
+var archiveImportCmd = &cobra.Command{
+	Use:   "import <yt-dlp archive file>",
+	Short: "Import a yt-dlp --download-archive file",
+	Long: `Import the video IDs listed in a yt-dlp --download-archive file, so videos
+backed up with yt-dlp directly are not downloaded again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		downloadArchive, err := openOutputArchive()
+		if err != nil {
+			return err
+		}
+
+		imported, skipped, err := downloadArchive.ImportYtDlp(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Imported %d video(s) into %s\n", imported, downloadArchive.Location())
+		if skipped > 0 {
+			fmt.Printf("⏭️  Skipped %d line(s) already archived or not from YouTube\n", skipped)
+		}
+		return nil
+	},
+}
+
+// openOutputArchive opens the archive of --output or the profile's output directory
+func openOutputArchive() (*archive.Archive, error) {
+	dir := archiveOutputDir
+	if dir == "" {
+		cfg, err := loadProfileConfig()
+		if err != nil {
+			return nil, err
+		}
+		dir = cfg.OutputDir
+	}
+	return archive.Open(dir)
+}
+
+func init() {
+	archiveCmd.AddCommand(archiveListCmd)
+	archiveCmd.AddCommand(archiveImportCmd)
+
+	archiveCmd.PersistentFlags().StringVarP(&archiveOutputDir, "output", "o", "", "Output directory holding the archive (default: the profile's output directory)")
+}
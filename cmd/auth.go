@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/AlienFacepalm/YeeTrap/internal/auth"
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
 	"github.com/spf13/cobra"
 )
 
 var (
-	showSetup bool
+	showSetup    bool
+	noBrowser    bool
+	useDevice    bool
+	authFeatures []string
 )
 
 var authCmd = &cobra.Command{
@@ -17,8 +24,16 @@ var authCmd = &cobra.Command{
 	Long: `Authenticate with YouTube using OAuth2. This will open a browser window for you to log in.
 
 YeeTrap uses Google OAuth2 to securely access your YouTube channel data.
+A temporary local listener captures the authorization automatically.
 The authentication token is saved locally and reused for future sessions.
 
+If the browser cannot reach this machine, use --no-browser to paste the code manually.
+On headless servers, use --device to approve the login from another device.
+
+Read-only access is requested by default. Use --feature to grant permissions for
+additional features up front (e.g. --feature captions); previously granted
+permissions are kept.
+
 If you haven't set up OAuth2 credentials yet, use: yeetrap auth --setup`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if showSetup {
@@ -31,19 +46,28 @@ If you haven't set up OAuth2 credentials yet, use: yeetrap auth --setup`,
 		fmt.Println()
 		
 		// Check if credentials exist
-		if err := auth.ValidateCredentials(); err != nil {
-			fmt.Printf("❌ %v\n\n", err)
+		if err := auth.ValidateCredentials(useDevice); err != nil {
+			fmt.Printf("❌ %s\n\n", errors.FormatError(err))
 			fmt.Println("💡 Run 'yeetrap auth --setup' for detailed setup instructions")
 			return err
 		}
 		
-		authenticator, err := auth.NewAuthenticator()
+		var requested []auth.Feature
+		for _, name := range authFeatures {
+			feature, err := auth.ParseFeature(name)
+			if err != nil {
+				return err
+			}
+			requested = append(requested, feature)
+		}
+
+		authenticator, err := auth.NewAuthenticator(requested...)
 		if err != nil {
 			return fmt.Errorf("failed to create authenticator: %w", err)
 		}
 
-		if err := authenticator.Authenticate(); err != nil {
-			return fmt.Errorf("authentication failed: %w", err)
+		if err := runAuthFlow(authenticator, noBrowser, useDevice); err != nil {
+			return err
 		}
 
 		fmt.Println()
@@ -54,8 +78,128 @@ If you haven't set up OAuth2 credentials yet, use: yeetrap auth --setup`,
 	},
 }
 
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the stored authentication token",
+	Long:  `Show when the stored access token expires, which scopes were granted, and whether a refresh token is available.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		authenticator, err := auth.NewAuthenticator()
+		if err != nil {
+			return fmt.Errorf("failed to create authenticator: %w", err)
+		}
+
+		status, err := authenticator.Status()
+		if err != nil {
+			fmt.Println("❌ Not authenticated")
+			fmt.Println("💡 Run 'yeetrap auth' to authenticate")
+			return err
+		}
+
+		fmt.Println("🔐 YeeTrap Authentication Status")
+		fmt.Println("================================")
+		fmt.Printf("Token file:    %s\n", status.Location)
+
+		switch {
+		case status.Expiry.IsZero():
+			fmt.Println("Access token:  no expiry recorded")
+		case status.Expired:
+			fmt.Printf("Access token:  expired %v ago (%s)\n",
+				time.Since(status.Expiry).Round(time.Second), status.Expiry.Local().Format(time.RFC1123))
+		default:
+			fmt.Printf("Access token:  valid for %v (until %s)\n",
+				time.Until(status.Expiry).Round(time.Second), status.Expiry.Local().Format(time.RFC1123))
+		}
+
+		if status.HasRefreshToken {
+			fmt.Println("Refresh token: ✅ present (access token renews automatically)")
+		} else {
+			fmt.Println("Refresh token: ❌ missing (run 'yeetrap auth' again when the access token expires)")
+		}
+
+		if len(status.Scopes) == 0 {
+			fmt.Println("Scopes:        unknown (re-run 'yeetrap auth' to record them)")
+		} else {
+			fmt.Println("Scopes:")
+			for _, scope := range status.Scopes {
+				fmt.Printf("  - %s\n", scope)
+			}
+		}
+
+		return nil
+	},
+}
+
+var authRevokeCmd = &cobra.Command{
+	Use:     "revoke",
+	Aliases: []string{"logout"},
+	Short:   "Revoke YeeTrap's access and delete the stored token",
+	Long: `Revoke YeeTrap's access to your Google account at the OAuth2 revocation
+endpoint and delete the locally stored token.
+
+The local token is deleted even if remote revocation fails; in that case the
+grant can be removed manually from your Google account permissions page.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		authenticator, err := auth.NewAuthenticator()
+		if err != nil {
+			return fmt.Errorf("failed to create authenticator: %w", err)
+		}
+
+		result, err := authenticator.Revoke()
+		if result == nil {
+			return err
+		}
+
+		switch {
+		case result.RemoteRevoked:
+			fmt.Println("✅ Access revoked at Google")
+		case result.AlreadyInvalid:
+			fmt.Println("✅ Token was already revoked or expired at Google")
+		default:
+			fmt.Printf("❌ Remote revocation failed: %v\n", result.RemoteErr)
+			fmt.Printf("💡 Remove YeeTrap's access manually at %s\n", constants.GooglePermissionsURL)
+		}
+
+		if err != nil {
+			fmt.Printf("❌ Local token could not be deleted: %v\n", err)
+			return err
+		}
+		fmt.Println("🗑️  Local token deleted")
+
+		if result.RemoteErr != nil {
+			return fmt.Errorf("remote revocation failed: %w", result.RemoteErr)
+		}
+		return nil
+	},
+}
+
+// runAuthFlow runs the browser, manual or device authorization flow
+func runAuthFlow(authenticator *auth.Authenticator, manual, device bool) error {
+	if manual && device {
+		return fmt.Errorf("--no-browser and --device cannot be used together")
+	}
+
+	authenticate := authenticator.Authenticate
+	switch {
+	case device:
+		authenticate = authenticator.AuthenticateDevice
+	case manual:
+		authenticate = authenticator.AuthenticateManual
+	}
+
+	if err := authenticate(); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	return nil
+}
+
 func init() {
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authRevokeCmd)
+
 	authCmd.Flags().BoolVar(&showSetup, "setup", false, "Show detailed OAuth2 setup instructions")
+	authCmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Print the login URL and paste the authorization code manually")
+	authCmd.Flags().BoolVar(&useDevice, "device", false, "Use the device authorization flow for headless machines")
+	authCmd.Flags().StringSliceVar(&authFeatures, "feature", nil, fmt.Sprintf("Also request permissions for these features (%s)", strings.Join(auth.Features(), ", ")))
 }
 
 
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/auth"
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// newYouTubeService creates a YouTube service, using an API key when one is
+// given via --api-key or YEETRAP_API_KEY and OAuth2 otherwise
+func newYouTubeService(features ...auth.Feature) (*youtube.Service, error) {
+	if key := resolveAPIKey(); key != "" {
+		if len(features) > 0 {
+			return nil, fmt.Errorf("%s requires OAuth2 and cannot be used with an API key", features[0])
+		}
+
+		ytService, err := youtube.NewServiceWithAPIKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create YouTube service: %w", err)
+		}
+		return ytService, nil
+	}
+
+	client, err := authenticatedClient(features...)
+	if err != nil {
+		return nil, err
+	}
+
+	ytService, err := youtube.NewService(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create YouTube service: %w", err)
+	}
+	return ytService, nil
+}
+
+// resolveChannel turns a channel ID, @handle or channel URL into a channel ID.
+// An empty value stays empty, meaning the authenticated user's channel.
+func resolveChannel(ctx context.Context, ytService *youtube.Service, value string) (string, error) {
+	ref, err := youtube.ParseChannelRef(value)
+	if value == "" || err != nil {
+		return "", err
+	}
+	if ref.ID != "" {
+		return ref.ID, nil
+	}
+
+	channelID, err := ytService.ResolveChannel(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve channel: %w", err)
+	}
+
+	fmt.Printf("🔎 Resolved %s to channel ID %s\n", ref, channelID)
+	return channelID, nil
+}
+
+// resolveAPIKey returns the API key from the flag or the environment
+func resolveAPIKey() string {
+	if apiKey != "" {
+		return apiKey
+	}
+	return os.Getenv(constants.APIKeyEnvVar)
+}
+
+// authenticatedClient returns an HTTP client whose token covers the given
+// features. When the stored token lacks a required scope and we are running
+// interactively, the user is offered an incremental re-consent.
+func authenticatedClient(features ...auth.Feature) (*http.Client, error) {
+	authenticator, err := auth.NewAuthenticator(features...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticator: %w", err)
+	}
+
+	client, err := authenticator.GetClient()
+	if err == nil {
+		return client, nil
+	}
+
+	missing := auth.MissingScopes(err)
+	if missing == nil {
+		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
+	}
+
+	fmt.Println("🔐 This command needs additional YouTube permissions:")
+	for _, scope := range missing {
+		fmt.Printf("   - %s\n", scope)
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
+	}
+
+	if !confirm("🤔 Grant them now? (Y/n): ", true) {
+		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
+	}
+
+	if err := authenticator.Authenticate(); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	client, err = authenticator.GetClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
+	}
+	return client, nil
+}
+
+// confirm asks a yes/no question on stdin, returning def on an empty answer
+func confirm(prompt string, def bool) bool {
+	fmt.Print(prompt)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return def
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorJSON      bool
+	doctorOutputDir string
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the YeeTrap environment",
+	Long: `Check credentials, the stored token, the config file, the output directory,
+yt-dlp, ffmpeg and YouTube API reachability, and report pass/warn/fail for each.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report := doctor.Run(doctor.Options{OutputDir: doctorOutputDir})
+
+		if doctorJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+		} else {
+			printDoctorReport(report)
+		}
+
+		if report.HasFailures() {
+			return fmt.Errorf("one or more checks failed")
+		}
+		return nil
+	},
+}
+
+// printDoctorReport renders the report as a table
+func printDoctorReport(report *doctor.Report) {
+	fmt.Printf("🩺 YeeTrap Doctor (profile: %s)\n", report.Profile)
+	fmt.Println("===============================")
+	fmt.Println()
+
+	for _, result := range report.Results {
+		fmt.Printf("%s %-4s  %-16s %s\n", statusIcon(result.Status), result.Status, result.Name, result.Message)
+		if result.Details != "" {
+			fmt.Printf("   %-4s  %-16s 💡 %s\n", "", "", result.Details)
+		}
+	}
+}
+
+// statusIcon returns the symbol for a check status
+func statusIcon(status doctor.Status) string {
+	switch status {
+	case doctor.StatusPass:
+		return "✅"
+	case doctor.StatusWarn:
+		return "⚠️ "
+	default:
+		return "❌"
+	}
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+	doctorCmd.Flags().StringVarP(&doctorOutputDir, "output", "o", "", "Output directory to check (default: the profile's output directory)")
+}
@@ -1,51 +1,146 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/AlienFacepalm/YeeTrap/internal/auth"
 	"github.com/AlienFacepalm/YeeTrap/internal/downloader"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/filter"
 	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
 	"github.com/spf13/cobra"
 )
 
 var (
 	downloadChannelID string
+	downloadPlaylist  string
+	numberFiles       bool
 	downloadMaxVideos int64
 	outputDir         string
 	quality           string
 	concurrent        int
+	withCaptions      bool
+	fromFile          string
+	forceDownload     bool
+	resumeDownload    bool
+	downloadProgress  progressOptions
+	downloadLayout    layoutOptions
+	downloadFormat    formatOptions
+	downloadFilter    filter.Options
 )
 
+// videoEntry is a video reference given on the command line or in a list file
+type videoEntry struct {
+	source string
+	value  string
+}
+
 var downloadCmd = &cobra.Command{
-	Use:   "download",
+	Use:   "download [video...]",
 	Short: "Download videos from a YouTube channel",
-	Long:  `Download all videos from your authenticated YouTube channel for backup purposes.`,
+	Long: `Download all videos from your authenticated YouTube channel for backup purposes.
+
+Use --playlist to download a playlist instead, and --number to prefix file
+names with each video's playlist position so they sort in playlist order.
+
+To download specific videos, pass their IDs or URLs as arguments, or list them
+in a file with --from-file (one per line, '#' starts a comment, '-' reads
+standard input).
+
+Press Ctrl-C once to stop starting new downloads while those in progress
+finish, twice to stop those too. Videos left unfinished are listed in
+.yeetrap/unfinished.txt in the output directory; --resume downloads them.` + "\n" + filterHelp + "\n" + formatHelp + "\n" + layoutHelp + "\n" + progressHelp,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Create output directory if it doesn't exist
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+		entries, err := collectVideoEntries(args, fromFile)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 && (cmd.Flags().Changed("channel") || cmd.Flags().Changed("playlist")) {
+			return errors.NewValidationError("specific videos cannot be combined with --channel or --playlist")
+		}
+		if resumeDownload && (len(entries) > 0 || cmd.Flags().Changed("channel") || cmd.Flags().Changed("playlist")) {
+			return errors.NewValidationError("--resume cannot be combined with specific videos, --channel or --playlist")
 		}
 
-		authenticator, err := auth.NewAuthenticator()
+		// Reject malformed IDs before anything is authenticated or downloaded
+		videoIDs, err := parseVideoEntries(entries)
 		if err != nil {
-			return fmt.Errorf("failed to create authenticator: %w", err)
+			return err
 		}
 
-		client, err := authenticator.GetClient()
+		keep, err := filter.Build(downloadFilter)
 		if err != nil {
-			return fmt.Errorf("failed to get authenticated client: %w", err)
+			return err
 		}
 
-		ytService, err := youtube.NewService(client)
+		if err := downloadProgress.validate(); err != nil {
+			return err
+		}
+
+		cfg, err := loadProfileConfig()
 		if err != nil {
-			return fmt.Errorf("failed to create YouTube service: %w", err)
+			return err
+		}
+		if !cmd.Flags().Changed("channel") {
+			downloadChannelID = cfg.DefaultChannelID
+		}
+		if !cmd.Flags().Changed("output") {
+			outputDir = cfg.OutputDir
+		}
+		if !cmd.Flags().Changed("quality") {
+			quality = cfg.DefaultQuality
+		}
+		if !cmd.Flags().Changed("concurrent") {
+			concurrent = cfg.MaxConcurrent
+		}
+		formats, err := downloadFormat.resolve(cmd, cfg, quality)
+		if err != nil {
+			return err
+		}
+		outputTemplate, err := downloadLayout.resolve(cmd, cfg)
+		if err != nil {
+			return err
+		}
+
+		if resumeDownload {
+			if videoIDs, err = resumeVideoIDs(outputDir); err != nil {
+				return err
+			}
 		}
 
-		videos, err := ytService.ListChannelVideos(downloadChannelID, downloadMaxVideos)
+		// Create output directory if it doesn't exist
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+
+		var features []auth.Feature
+		if withCaptions {
+			features = append(features, auth.FeatureCaptions)
+		}
+
+		ytService, err := newYouTubeService(features...)
+		if err != nil {
+			return err
+		}
+
+		var videos []youtube.Video
+		if len(videoIDs) > 0 {
+			videos, err = getVideos(cmd.Context(), ytService, videoIDs)
+			videos = applyFilter(videos, keep)
+		} else {
+			videos, err = listVideos(ytService, cmd, downloadChannelID, downloadPlaylist, downloadMaxVideos, keep)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to list videos: %w", err)
+			return err
+		}
+		if len(videos) == 0 {
+			fmt.Println("No videos to download")
+			return nil
 		}
 
 		fmt.Printf("Found %d videos to download\n\n", len(videos))
@@ -54,22 +149,179 @@ var downloadCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create downloader: %w", err)
 		}
+		dl.SetNumbered(numberFiles)
+		dl.SetForce(forceDownload)
+		dl.SetOutputTemplate(outputTemplate, downloadLayout.PerVideoDir)
+		dl.SetFormats(formats)
+		backend, err := downloader.SelectBackend(cmd.Context(), cfg.Backend, cfg.BackendPath, cfg.BackendArgs)
+		if err != nil {
+			return err
+		}
+		dl.SetBackend(backend)
+		closeProgress, err := applyProgress(dl, downloadProgress)
+		if err != nil {
+			return err
+		}
+		defer closeProgress()
 		
-		if err := dl.DownloadVideos(videos); err != nil {
+		if err := dl.DownloadVideos(cmd.Context(), videos); err != nil {
 			return fmt.Errorf("download failed: %w", err)
 		}
 
+		if withCaptions {
+			fmt.Println("\nDownloading caption tracks...")
+			if err := dl.DownloadCaptions(videos, ytService); err != nil {
+				return fmt.Errorf("caption download failed: %w", err)
+			}
+		}
+
 		fmt.Println("\n✓ All downloads completed!")
 		return nil
 	},
 }
 
+// resumeVideoIDs returns the videos interrupted downloads into outputDir
+// left unfinished
+func resumeVideoIDs(outputDir string) ([]string, error) {
+	path := downloader.UnfinishedPath(outputDir)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, errors.NewValidationError(fmt.Sprintf("no unfinished downloads to resume in %s", outputDir))
+	}
+
+	entries, err := collectVideoEntries(nil, path)
+	if err != nil {
+		return nil, err
+	}
+	videoIDs, err := parseVideoEntries(entries)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("▶️  Resuming %d unfinished video(s)\n", len(videoIDs))
+	return videoIDs, nil
+}
+
+// collectVideoEntries gathers video references from arguments and a list file
+func collectVideoEntries(args []string, path string) ([]videoEntry, error) {
+	var entries []videoEntry
+	for i, arg := range args {
+		entries = append(entries, videoEntry{source: fmt.Sprintf("argument %d", i+1), value: arg})
+	}
+
+	if path == "" {
+		return entries, nil
+	}
+
+	var r io.Reader = os.Stdin
+	name := "stdin"
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, errors.WrapFile(err, "unable to open video list")
+		}
+		defer file.Close()
+		r = file
+		name = path
+	}
+
+	fileEntries, err := readVideoList(r, name)
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, fileEntries...), nil
+}
+
+// readVideoList reads one video ID or URL per line. Blank lines are skipped
+// and '#' starts a comment, either on its own line or after the entry.
+func readVideoList(r io.Reader, name string) ([]videoEntry, error) {
+	var entries []videoEntry
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t') {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		entries = append(entries, videoEntry{source: fmt.Sprintf("%s:%d", name, line), value: text})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WrapFile(err, "unable to read video list")
+	}
+	return entries, nil
+}
+
+// parseVideoEntries turns references into unique video IDs, reporting every
+// invalid entry at once
+func parseVideoEntries(entries []videoEntry) ([]string, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	invalid := 0
+
+	for _, entry := range entries {
+		id, err := youtube.ParseVideoID(entry.value)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", entry.source, err)
+			invalid++
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if invalid > 0 {
+		return nil, errors.NewValidationError(fmt.Sprintf("%d invalid video reference(s)", invalid))
+	}
+	return ids, nil
+}
+
+// getVideos fetches metadata for specific videos, warning about any that are
+// missing or not accessible
+func getVideos(ctx context.Context, ytService *youtube.Service, ids []string) ([]youtube.Video, error) {
+	videos, err := ytService.GetVideos(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video details: %w", err)
+	}
+
+	if len(videos) < len(ids) {
+		found := make(map[string]bool, len(videos))
+		for _, video := range videos {
+			found[video.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				fmt.Printf("⚠️  Video %s not found or not accessible, skipping\n", id)
+			}
+		}
+	}
+
+	if len(videos) == 0 {
+		return nil, errors.NewAPIError("none of the requested videos could be found")
+	}
+	return videos, nil
+}
+
 func init() {
-	downloadCmd.Flags().StringVarP(&downloadChannelID, "channel", "c", "", "YouTube channel ID (leave empty to use authenticated user's channel)")
+	downloadCmd.Flags().StringVarP(&downloadChannelID, "channel", "c", "", "YouTube channel ID, @handle or URL (leave empty to use authenticated user's channel)")
+	downloadCmd.Flags().StringVarP(&downloadPlaylist, "playlist", "p", "", "Playlist ID or URL to download instead of channel uploads")
+	downloadCmd.Flags().BoolVar(&numberFiles, "number", false, "Prefix file names with the playlist position (e.g. \"007 - Title\")")
 	downloadCmd.Flags().Int64VarP(&downloadMaxVideos, "max", "m", 50, "Maximum number of videos to download")
 	downloadCmd.Flags().StringVarP(&outputDir, "output", "o", "./downloads", "Output directory for downloaded videos")
 	downloadCmd.Flags().StringVarP(&quality, "quality", "q", "best", "Video quality (best, 1080p, 720p, 480p)")
 	downloadCmd.Flags().IntVarP(&concurrent, "concurrent", "j", 3, "Number of concurrent downloads")
+	downloadCmd.Flags().StringVar(&fromFile, "from-file", "", "Read video IDs or URLs to download from a file, one per line ('-' for stdin)")
+	downloadCmd.Flags().BoolVar(&forceDownload, "force", false, "Download videos again even if the archive lists them as backed up")
+	downloadCmd.Flags().BoolVar(&resumeDownload, "resume", false, "Download the videos an interrupted run left unfinished")
+	addFormatFlags(downloadCmd, &downloadFormat)
+	addLayoutFlags(downloadCmd, &downloadLayout)
+	addProgressFlags(downloadCmd, &downloadProgress)
+	addFilterFlags(downloadCmd, &downloadFilter)
+	downloadCmd.Flags().BoolVar(&withCaptions, "captions", false, "Also download caption tracks via the Captions API (owner only, costs API quota)")
 }
 
 
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/filter"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// filterHelp describes the filter flags for command help texts
+const filterHelp = `
+Filters:
+  --since / --until take a date (2024-01-31), a timestamp or an age (7d, 48h).
+  --filter takes an expression over video fields, e.g.
+    duration > 10m && privacy == "unlisted"
+    !short && (title =~ '(?i)tutorial' || tags contains "howto")
+  Fields: id, title, description, channel, published, duration, privacy,
+  upload_status, tags, category, language, definition, captions, live, views,
+  likes, position, short. Operators: && || ! == != < <= > >= =~ !~ contains.`
+
+// addFilterFlags registers the video filter flags on a command
+func addFilterFlags(cmd *cobra.Command, opts *filter.Options) {
+	cmd.Flags().StringVar(&opts.Since, "since", "", "Only videos published at or after this date or age (e.g. 2024-01-31, 30d)")
+	cmd.Flags().StringVar(&opts.Until, "until", "", "Only videos published before this date or age")
+	cmd.Flags().StringVar(&opts.Match, "match", "", "Only videos whose title matches this regular expression")
+	cmd.Flags().StringVar(&opts.Exclude, "exclude", "", "Skip videos whose title matches this regular expression")
+	cmd.Flags().StringSliceVar(&opts.Privacy, "privacy", nil, "Only videos with these privacy statuses (public, unlisted, private)")
+	cmd.Flags().StringVar(&opts.MinDuration, "min-duration", "", "Only videos at least this long (e.g. 90s, 10m)")
+	cmd.Flags().StringVar(&opts.MaxDuration, "max-duration", "", "Only videos at most this long")
+	cmd.Flags().StringVar(&opts.Expr, "filter", "", "Only videos matching this filter expression (see help)")
+}
+
+// applyFilter keeps the videos that pass the filter
+func applyFilter(videos []youtube.Video, keep youtube.VideoFilter) []youtube.Video {
+	if keep == nil {
+		return videos
+	}
+
+	kept := videos[:0]
+	for _, video := range videos {
+		if keep(video) {
+			kept = append(kept, video)
+		}
+	}
+	return kept
+}
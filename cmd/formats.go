@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/config"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+)
+
+// formatHelp describes the format flags for command help texts
+const formatHelp = `
+Formats:
+  --quality picks a built-in quality (best, 1080p, 720p, 480p); --format
+  passes a yt-dlp format selector instead, e.g. "bv*[height<=1440]+ba".
+  --format-profile picks a profile from format_profiles in the config file
+  (codecs, container, max height and fps, HDR, or audio only with codec and
+  bitrate). Repeat it to download each video in several formats; copies in
+  a profile are named like the video with " (<profile>)" appended.`
+
+// formatOptions holds the format flags of a command
+type formatOptions struct {
+	Format   string
+	Profiles []string
+}
+
+// addFormatFlags registers the format flags on a command
+func addFormatFlags(cmd *cobra.Command, opts *formatOptions) {
+	cmd.Flags().StringVar(&opts.Format, "format", "", "yt-dlp format selector to use instead of --quality")
+	cmd.Flags().StringArrayVar(&opts.Profiles, "format-profile", nil, "Format profile from the config file to download in (repeatable)")
+}
+
+// resolve returns the format profiles of a run. The quality, or --format,
+// is included unless only --format-profile is given.
+func (o *formatOptions) resolve(cmd *cobra.Command, cfg *config.Config, quality string) ([]*format.Profile, error) {
+	if o.Format != "" && cmd.Flags().Changed("quality") {
+		return nil, errors.NewValidationError("--format replaces --quality; use only one of them")
+	}
+
+	var profiles []*format.Profile
+	switch {
+	case o.Format != "":
+		profiles = append(profiles, format.Raw(o.Format))
+	case len(o.Profiles) == 0 || cmd.Flags().Changed("quality"):
+		if err := validation.ValidateQuality(quality); err != nil {
+			return nil, err
+		}
+		profile, _ := format.Builtin(quality)
+		profiles = append(profiles, profile)
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range o.Profiles {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		profile, err := format.Lookup(name, cfg.FormatProfiles)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	// Qualities and --format all write the video's main files
+	primary := ""
+	for _, profile := range profiles {
+		if !profile.Primary() {
+			continue
+		}
+		name := profile.Name
+		if name == "" {
+			name = "--format"
+		}
+		if primary != "" {
+			return nil, errors.NewValidationError(fmt.Sprintf("%s and %s would write the same files; use one built-in quality or --format per run", primary, name))
+		}
+		primary = name
+	}
+	return profiles, nil
+}
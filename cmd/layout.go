@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/config"
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/downloader"
+)
+
+// layoutHelp describes the file layout flags for command help texts
+const layoutHelp = `
+File layout:
+  --output-template is a Go template over the video, where '/' creates
+  folders, e.g. {{.PublishedAt.Year}}/{{.PublishedAt.Format "2006-01-02"}} - {{.Title}} [{{.ID}}]
+  Fields: ID, Title, Description, PublishedAt, ChannelID, ChannelTitle,
+  PlaylistIndex, Duration, PrivacyStatus, Tags, ViewCount and more.
+  The default is ` + constants.DefaultOutputTemplate + `; videos whose
+  names would collide get their ID appended.`
+
+// layoutOptions holds the file layout flags of a command
+type layoutOptions struct {
+	Template    string
+	PerVideoDir bool
+}
+
+// addLayoutFlags registers the file layout flags on a command
+func addLayoutFlags(cmd *cobra.Command, opts *layoutOptions) {
+	cmd.Flags().StringVar(&opts.Template, "output-template", constants.DefaultOutputTemplate, "Go template for file names relative to the output directory ('/' creates folders)")
+	cmd.Flags().BoolVar(&opts.PerVideoDir, "per-video-dir", false, "Put each video's files (video, description, info JSON, thumbnail) in a folder of their own")
+}
+
+// resolve fills unset layout flags from the profile config and parses the
+// output template
+func (o *layoutOptions) resolve(cmd *cobra.Command, cfg *config.Config) (*downloader.OutputTemplate, error) {
+	if !cmd.Flags().Changed("output-template") && cfg.OutputTemplate != "" {
+		o.Template = cfg.OutputTemplate
+	}
+	if !cmd.Flags().Changed("per-video-dir") {
+		o.PerVideoDir = cfg.PerVideoDir
+	}
+	return downloader.ParseOutputTemplate(o.Template)
+}
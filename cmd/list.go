@@ -3,56 +3,191 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/AlienFacepalm/YeeTrap/internal/auth"
-	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
 	"github.com/spf13/cobra"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/filter"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
 )
 
 var (
-	channelID string
-	maxVideos int64
+	channelID         string
+	playlistRef       string
+	maxVideos         int64
+	playlistChannelID string
+	listFilter        filter.Options
 )
 
 var listCmd = &cobra.Command{
-	Use:   "list",
+	Use:   "list [channel|playlist]",
 	Short: "List videos from a YouTube channel",
-	Long:  `List all videos from your authenticated YouTube channel.`,
+	Long: `List all videos from your authenticated YouTube channel.
+
+Use --channel to list another channel's uploads, or --playlist to list the
+videos of a playlist in playlist order. Channels may be given as a channel ID,
+an @handle or any youtube.com channel URL; playlists as an ID or URL. A single
+positional argument is accepted in place of either flag.` + "\n" + filterHelp,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		authenticator, err := auth.NewAuthenticator()
+		cfg, err := loadProfileConfig()
 		if err != nil {
-			return fmt.Errorf("failed to create authenticator: %w", err)
+			return err
+		}
+		if !cmd.Flags().Changed("channel") {
+			channelID = cfg.DefaultChannelID
+		}
+		if len(args) == 1 {
+			if err := applyTargetArg(cmd, args[0], &channelID, &playlistRef); err != nil {
+				return err
+			}
 		}
 
-		client, err := authenticator.GetClient()
+		keep, err := filter.Build(listFilter)
 		if err != nil {
-			return fmt.Errorf("failed to get authenticated client: %w", err)
+			return err
 		}
 
-		ytService, err := youtube.NewService(client)
+		ytService, err := newYouTubeService()
 		if err != nil {
-			return fmt.Errorf("failed to create YouTube service: %w", err)
+			return err
 		}
 
-		videos, err := ytService.ListChannelVideos(channelID, maxVideos)
+		videos, err := listVideos(ytService, cmd, channelID, playlistRef, maxVideos, keep)
 		if err != nil {
-			return fmt.Errorf("failed to list videos: %w", err)
+			return err
 		}
 
 		fmt.Printf("Found %d videos:\n\n", len(videos))
 		for i, video := range videos {
-			fmt.Printf("%d. %s\n", i+1, video.Title)
+			position := i + 1
+			if video.PlaylistIndex > 0 && playlistRef != "" {
+				position = video.PlaylistIndex
+			}
+			fmt.Printf("%d. %s\n", position, video.Title)
 			fmt.Printf("   ID: %s\n", video.ID)
 			fmt.Printf("   URL: https://www.youtube.com/watch?v=%s\n", video.ID)
-			fmt.Printf("   Published: %s\n\n", video.PublishedAt)
+			fmt.Printf("   Published: %s\n", video.PublishedAt.Local().Format("2006-01-02 15:04"))
+			fmt.Printf("   Duration: %v | Privacy: %s | Views: %d\n\n", video.Duration, video.PrivacyStatus, video.ViewCount)
 		}
 
 		return nil
 	},
 }
 
+var listPlaylistsCmd = &cobra.Command{
+	Use:   "playlists [channel]",
+	Short: "List the playlists of a YouTube channel",
+	Long: `List the playlists of your authenticated YouTube channel (including private
+ones), or the public playlists of another channel given with --channel or as
+an argument (channel ID, @handle or channel URL).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadProfileConfig()
+		if err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("channel") {
+			playlistChannelID = cfg.DefaultChannelID
+		}
+		if len(args) == 1 {
+			if cmd.Flags().Changed("channel") {
+				return errors.NewValidationError("pass the channel either as an argument or with --channel, not both")
+			}
+			playlistChannelID = args[0]
+		}
+
+		ytService, err := newYouTubeService()
+		if err != nil {
+			return err
+		}
+
+		resolvedID, err := resolveChannel(cmd.Context(), ytService, playlistChannelID)
+		if err != nil {
+			return err
+		}
+
+		playlists, err := ytService.ListPlaylists(cmd.Context(), resolvedID)
+		if err != nil {
+			return fmt.Errorf("failed to list playlists: %w", err)
+		}
+
+		fmt.Printf("Found %d playlists:\n\n", len(playlists))
+		for i, playlist := range playlists {
+			fmt.Printf("%d. %s\n", i+1, playlist.Title)
+			fmt.Printf("   ID: %s\n", playlist.ID)
+			fmt.Printf("   URL: https://www.youtube.com/playlist?list=%s\n", playlist.ID)
+			fmt.Printf("   Videos: %d | Privacy: %s\n\n", playlist.ItemCount, playlist.PrivacyStatus)
+		}
+
+		return nil
+	},
+}
+
+// applyTargetArg routes a positional channel or playlist reference into the
+// matching flag value. It conflicts with an explicitly set flag.
+func applyTargetArg(cmd *cobra.Command, arg string, channel, playlist *string) error {
+	if cmd.Flags().Changed("channel") || cmd.Flags().Changed("playlist") {
+		return errors.NewValidationError("pass the channel or playlist either as an argument or with a flag, not both")
+	}
+
+	ref, err := youtube.ParseRef(arg)
+	if err != nil {
+		return err
+	}
+
+	switch ref.Kind {
+	case youtube.RefChannel:
+		*channel = arg
+	case youtube.RefPlaylist:
+		*playlist = arg
+	default:
+		return errors.NewValidationError(fmt.Sprintf("%s is a %s, expected a channel or playlist", arg, ref.Kind))
+	}
+	return nil
+}
+
+// listVideos lists the videos of a playlist when one is given, otherwise the
+// uploads of a channel. The --channel and --playlist flags are exclusive.
+// Only videos passing keep count towards max.
+func listVideos(ytService *youtube.Service, cmd *cobra.Command, channel, playlist string, max int64, keep youtube.VideoFilter) ([]youtube.Video, error) {
+	if playlist == "" {
+		resolvedID, err := resolveChannel(cmd.Context(), ytService, channel)
+		if err != nil {
+			return nil, err
+		}
+
+		videos, err := ytService.ListChannelVideos(cmd.Context(), resolvedID, max, keep)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list videos: %w", err)
+		}
+		return videos, nil
+	}
+
+	if cmd.Flags().Changed("channel") {
+		return nil, errors.NewValidationError("--channel and --playlist cannot be used together")
+	}
+
+	playlistID, err := youtube.ParsePlaylistID(playlist)
+	if err != nil {
+		return nil, err
+	}
+
+	videos, err := ytService.ListPlaylistVideos(cmd.Context(), playlistID, max, keep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list playlist videos: %w", err)
+	}
+	return videos, nil
+}
+
 func init() {
-	listCmd.Flags().StringVarP(&channelID, "channel", "c", "", "YouTube channel ID (leave empty to use authenticated user's channel)")
+	listCmd.AddCommand(listPlaylistsCmd)
+
+	listCmd.Flags().StringVarP(&channelID, "channel", "c", "", "YouTube channel ID, @handle or URL (leave empty to use authenticated user's channel)")
+	listCmd.Flags().StringVarP(&playlistRef, "playlist", "p", "", "Playlist ID or URL to list instead of channel uploads")
 	listCmd.Flags().Int64VarP(&maxVideos, "max", "m", 50, "Maximum number of videos to list")
+	addFilterFlags(listCmd, &listFilter)
+
+	listPlaylistsCmd.Flags().StringVarP(&playlistChannelID, "channel", "c", "", "YouTube channel ID, @handle or URL (leave empty to use authenticated user's channel)")
 }
 
 
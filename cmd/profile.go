@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/config"
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/profile"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+	"github.com/spf13/cobra"
+)
+
+var (
+	profileChannelID  string
+	profileOutputDir  string
+	profileQuality    string
+	profileConcurrent int
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage account profiles",
+	Long: `Manage account profiles for backing up several channels or brand accounts.
+
+Each profile keeps its own OAuth2 credentials, authentication token and default
+settings under ~/.yeetrap/profiles/<name>. Select a profile for any command with
+--profile <name> or the YEETRAP_PROFILE environment variable.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List account profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := profile.List()
+		if err != nil {
+			return err
+		}
+
+		if len(profiles) == 0 {
+			fmt.Println("No profiles yet. Run 'yeetrap auth' to set up the default profile.")
+			return nil
+		}
+
+		fmt.Printf("Found %d profile(s):\n\n", len(profiles))
+		for _, p := range profiles {
+			marker := " "
+			if p.Default {
+				marker = "*"
+			}
+			fmt.Printf("%s %-20s credentials: %s  token: %s\n", marker, p.Name, checkMark(p.HasCredentials), checkMark(p.HasToken))
+		}
+		fmt.Println()
+		fmt.Println("* = default profile")
+		return nil
+	},
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Create a new account profile",
+	Long: `Create a new account profile. The OAuth2 client credentials of the default
+profile are copied into the new profile; authenticate it afterwards with
+'yeetrap --profile <name> auth'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check every setting first so a bad flag leaves no profile behind
+		cfg := config.DefaultConfig()
+		if cmd.Flags().Changed("channel") {
+			if _, err := youtube.ParseChannelRef(profileChannelID); err != nil {
+				return err
+			}
+			cfg.DefaultChannelID = profileChannelID
+		}
+		if cmd.Flags().Changed("output") {
+			if err := validation.ValidateOutputDir(profileOutputDir); err != nil {
+				return err
+			}
+			cfg.OutputDir = profileOutputDir
+		}
+		if cmd.Flags().Changed("quality") {
+			if err := validation.ValidateQuality(profileQuality); err != nil {
+				return err
+			}
+			cfg.DefaultQuality = profileQuality
+		}
+		if cmd.Flags().Changed("concurrent") {
+			if err := validation.ValidateConcurrency(profileConcurrent); err != nil {
+				return err
+			}
+			cfg.MaxConcurrent = profileConcurrent
+		}
+
+		info, err := profile.Add(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := cfg.SaveTo(filepath.Join(info.Dir, constants.ConfigFile)); err != nil {
+			if removeErr := profile.Remove(info.Name); removeErr != nil {
+				logger.Warn("Unable to remove incomplete profile %s: %v", info.Name, removeErr)
+			}
+			return err
+		}
+
+		fmt.Printf("✅ Created profile %s\n", info.Name)
+		if !info.HasCredentials {
+			fmt.Printf("💡 Place OAuth2 credentials at: %s\n", filepath.Join(info.Dir, constants.CredentialsFile))
+		}
+		fmt.Printf("💡 Authenticate it with: yeetrap --profile %s auth\n", info.Name)
+		return nil
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove an account profile and its stored token",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := profile.Remove(args[0]); err != nil {
+			return err
+		}
+
+		fmt.Printf("🗑️  Removed profile %s\n", args[0])
+		return nil
+	},
+}
+
+var profileDefaultCmd = &cobra.Command{
+	Use:   "default [name]",
+	Short: "Show or set the default account profile",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			name, err := profile.Default()
+			if err != nil {
+				return err
+			}
+			fmt.Println(name)
+			return nil
+		}
+
+		if err := profile.SetDefault(args[0]); err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Default profile set to %s\n", args[0])
+		return nil
+	},
+}
+
+// loadProfileConfig loads the default settings of the active profile
+func loadProfileConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile settings: %w", err)
+	}
+	return cfg, nil
+}
+
+// checkMark renders a boolean as a status symbol
+func checkMark(ok bool) string {
+	if ok {
+		return "✅"
+	}
+	return "❌"
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileRemoveCmd)
+	profileCmd.AddCommand(profileDefaultCmd)
+
+	profileAddCmd.Flags().StringVarP(&profileChannelID, "channel", "c", "", "Default channel ID, @handle or URL for this profile")
+	profileAddCmd.Flags().StringVarP(&profileOutputDir, "output", "o", constants.DefaultOutputDir, "Default output directory for this profile")
+	profileAddCmd.Flags().StringVarP(&profileQuality, "quality", "q", constants.DefaultQuality, "Default video quality for this profile")
+	profileAddCmd.Flags().IntVarP(&profileConcurrent, "concurrent", "j", constants.DefaultConcurrency, "Default number of concurrent downloads for this profile")
+}
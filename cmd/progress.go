@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/downloader"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/progress"
+)
+
+// progressHelp describes the progress flags for command help texts
+const progressHelp = `
+Progress:
+  --progress=json writes newline-delimited JSON events (run_started,
+  video_queued, video_progress, video_retry, video_completed, video_failed,
+  run_finished) to standard error, or to the file or FIFO named by
+  --progress-output.`
+
+// progressOptions holds the progress flags of a command
+type progressOptions struct {
+	Mode   string
+	Output string
+	Plain  bool
+}
+
+// addProgressFlags registers the progress flags on a command
+func addProgressFlags(cmd *cobra.Command, opts *progressOptions) {
+	cmd.Flags().StringVar(&opts.Mode, "progress", progress.ModeAuto, "Progress output: auto (dashboard on terminals), plain, json (NDJSON events)")
+	cmd.Flags().StringVar(&opts.Output, "progress-output", "", "File or FIFO to write --progress=json events to (default: standard error)")
+	cmd.Flags().BoolVar(&opts.Plain, "plain", false, "Print progress line by line instead of the live dashboard (same as --progress=plain)")
+}
+
+// validate checks the progress flags and resolves --plain into the mode
+func (o *progressOptions) validate() error {
+	if o.Plain {
+		if o.Mode != progress.ModeAuto && o.Mode != progress.ModePlain {
+			return errors.NewValidationError("--plain cannot be combined with --progress=" + o.Mode)
+		}
+		o.Mode = progress.ModePlain
+	}
+	if err := progress.ValidateMode(o.Mode); err != nil {
+		return err
+	}
+	if o.Output != "" && o.Mode != progress.ModeJSON {
+		return errors.NewValidationError("--progress-output requires --progress=json")
+	}
+	return nil
+}
+
+// applyProgress configures the downloader's progress output, opening the
+// event file if one was given. The returned function closes it.
+func applyProgress(dl *downloader.Downloader, opts progressOptions) (func(), error) {
+	var events io.Writer = os.Stderr
+	closeEvents := func() {}
+
+	if opts.Output != "" {
+		// O_APPEND keeps earlier runs in a regular file and works for FIFOs,
+		// where opening blocks until a reader is attached
+		file, err := os.OpenFile(opts.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, errors.WrapFile(err, "unable to open progress output")
+		}
+		events = file
+		closeEvents = func() { file.Close() }
+	}
+
+	dl.SetProgress(opts.Mode, events)
+	return closeEvents, nil
+}
@@ -1,26 +1,74 @@
 package cmd
 
 import (
+	"context"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/interrupt"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/profile"
 	"github.com/spf13/cobra"
 )
 
+var (
+	profileName string
+	apiKey      string
+	interrupted bool
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "yeetrap",
 	Short: "YeeTrap - YouTube Video Downloader for Content Creators",
 	Long: `YeeTrap is a tool for YouTube content creators to download their videos in bulk for backup purposes.
 It authenticates with YouTube and allows you to download all videos from your channel.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return selectProfile()
+	},
 }
 
-// Execute runs the root command
+// Execute runs the root command. SIGINT and SIGTERM cancel the command's
+// context, so long operations can stop cleanly.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := interrupt.WithSignals(context.Background())
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
+	interrupted = ctx.Err() != nil
+	return err
+}
+
+// Interrupted reports whether the last Execute was stopped by a signal
+func Interrupted() bool {
+	return interrupted
+}
+
+// selectProfile migrates legacy single-account files and activates the chosen profile
+func selectProfile() error {
+	if err := profile.Migrate(); err != nil {
+		return err
+	}
+
+	name, err := profile.Resolve(profileName)
+	if err != nil {
+		return err
+	}
+
+	constants.SetActiveProfile(name)
+	logger.Debug("Using profile %s", name)
+	return nil
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "YouTube Data API key for reading public channels without OAuth2 (or set YEETRAP_API_KEY)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Account profile to use (default: $YEETRAP_PROFILE or the default profile)")
+
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(profileCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(downloadCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(versionCmd)
 }
-
@@ -4,9 +4,17 @@ import (
 	"fmt"
 
 	"github.com/AlienFacepalm/YeeTrap/internal/auth"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
 	"github.com/spf13/cobra"
 )
 
+var (
+	credentialsFile string
+	setupRunAuth    bool
+	setupNoBrowser  bool
+	setupDevice     bool
+)
+
 var setupCmd = &cobra.Command{
 	Use:   "setup",
 	Short: "Set up YeeTrap OAuth2 app with Google Cloud Console",
@@ -20,14 +28,23 @@ The setup process includes:
 2. Enabling YouTube Data API v3
 3. Creating OAuth2 desktop app credentials
 4. Downloading and placing the credentials file
-5. Testing the authentication`,
+5. Testing the authentication
+
+For scripted provisioning, pass the downloaded client secret file directly.
+It is validated, installed with 0600 permissions, and no prompts are shown:
+
+  yeetrap setup --credentials-file ./client_secret.json [--auth [--device]]`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if credentialsFile != "" {
+			return setupFromFile()
+		}
+
 		fmt.Println("🚀 YeeTrap OAuth2 App Setup")
 		fmt.Println("============================")
 		fmt.Println()
 		
 		// Check if already set up
-		if err := auth.ValidateCredentials(); err == nil {
+		if err := auth.ValidateCredentials(false); err == nil {
 			fmt.Println("✅ OAuth2 credentials are already set up!")
 			fmt.Println("💡 You can now run: yeetrap auth")
 			return nil
@@ -46,8 +63,8 @@ The setup process includes:
 			fmt.Println()
 			fmt.Println("🧪 Testing OAuth2 setup...")
 			
-			if err := auth.ValidateCredentials(); err != nil {
-				fmt.Printf("❌ Setup incomplete: %v\n", err)
+			if err := auth.ValidateCredentials(false); err != nil {
+				fmt.Printf("❌ Setup incomplete: %s\n", errors.FormatError(err))
 				fmt.Println("💡 Please complete the setup steps above and try again")
 				return err
 			}
@@ -63,3 +80,49 @@ The setup process includes:
 		return nil
 	},
 }
+
+// setupFromFile installs a client secret file without any prompts and
+// optionally runs the authentication flow
+func setupFromFile() error {
+	fmt.Println("🚀 YeeTrap OAuth2 App Setup")
+	fmt.Println("============================")
+	fmt.Println()
+
+	credPath, app, err := auth.InstallCredentials(credentialsFile, setupDevice)
+	if err != nil {
+		fmt.Printf("❌ %s\n", errors.FormatError(err))
+		return err
+	}
+
+	fmt.Printf("✅ Credentials for project %q installed to: %s\n", app.ProjectID, credPath)
+
+	if !setupRunAuth {
+		if setupDevice && app.CheckBrowserFlow() != nil {
+			fmt.Println("💡 This client only supports the device flow. You can now run: yeetrap auth --device")
+		} else {
+			fmt.Println("💡 You can now run: yeetrap auth")
+		}
+		return nil
+	}
+
+	fmt.Println()
+	authenticator, err := auth.NewAuthenticator()
+	if err != nil {
+		return fmt.Errorf("failed to create authenticator: %w", err)
+	}
+
+	if err := runAuthFlow(authenticator, setupNoBrowser, setupDevice); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("🎉 Setup complete! You can now use 'yeetrap list' and 'yeetrap download'")
+	return nil
+}
+
+func init() {
+	setupCmd.Flags().StringVar(&credentialsFile, "credentials-file", "", "Validate and install this OAuth2 client secret file without prompting")
+	setupCmd.Flags().BoolVar(&setupRunAuth, "auth", false, "Run the authentication flow after installing --credentials-file")
+	setupCmd.Flags().BoolVar(&setupNoBrowser, "no-browser", false, "With --auth, paste the authorization code manually")
+	setupCmd.Flags().BoolVar(&setupDevice, "device", false, "Accept clients that only support the device flow, and use it with --auth")
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/downloader"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/mirror"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncChannelID  string
+	syncOutputDir  string
+	syncQuality    string
+	syncConcurrent int
+	syncPrune      string
+	syncDryRun     bool
+	syncProgress   progressOptions
+	syncLayout     layoutOptions
+	syncFormat     formatOptions
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [channel]",
+	Short: "Mirror a channel into the output directory",
+	Long: `Mirror a channel into the output directory: download videos that are new on
+the channel, and record videos that were removed, made private or retitled in
+the sync state file (<output>/.yeetrap/sync.json).
+
+Downloaded videos are matched by the video ID in their .info.json files. Files
+of removed videos are kept by default; --prune=move relocates them to
+<output>/_removed/ and --prune=delete deletes them.` + "\n" + formatHelp + "\n" + layoutHelp + "\n" + progressHelp,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadProfileConfig()
+		if err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("channel") {
+			syncChannelID = cfg.DefaultChannelID
+		}
+		if len(args) == 1 {
+			if cmd.Flags().Changed("channel") {
+				return errors.NewValidationError("pass the channel either as an argument or with --channel, not both")
+			}
+			syncChannelID = args[0]
+		}
+		if !cmd.Flags().Changed("output") {
+			syncOutputDir = cfg.OutputDir
+		}
+		if !cmd.Flags().Changed("quality") {
+			syncQuality = cfg.DefaultQuality
+		}
+		if !cmd.Flags().Changed("concurrent") {
+			syncConcurrent = cfg.MaxConcurrent
+		}
+		formats, err := syncFormat.resolve(cmd, cfg, syncQuality)
+		if err != nil {
+			return err
+		}
+		outputTemplate, err := syncLayout.resolve(cmd, cfg)
+		if err != nil {
+			return err
+		}
+
+		if err := mirror.ValidatePruneMode(syncPrune); err != nil {
+			return err
+		}
+		if err := syncProgress.validate(); err != nil {
+			return err
+		}
+
+		dl, err := downloader.NewDownloader(syncOutputDir, syncQuality, syncConcurrent)
+		if err != nil {
+			return fmt.Errorf("failed to create downloader: %w", err)
+		}
+		dl.SetOutputTemplate(outputTemplate, syncLayout.PerVideoDir)
+		dl.SetFormats(formats)
+		backend, err := downloader.SelectBackend(cmd.Context(), cfg.Backend, cfg.BackendPath, cfg.BackendArgs)
+		if err != nil {
+			return err
+		}
+		dl.SetBackend(backend)
+		closeProgress, err := applyProgress(dl, syncProgress)
+		if err != nil {
+			return err
+		}
+		defer closeProgress()
+
+		ytService, err := newYouTubeService()
+		if err != nil {
+			return err
+		}
+
+		resolvedID, err := resolveChannel(cmd.Context(), ytService, syncChannelID)
+		if err != nil {
+			return err
+		}
+
+		remote, err := ytService.ListChannelVideos(cmd.Context(), resolvedID, 0, nil)
+		if err != nil {
+			return fmt.Errorf("failed to list videos: %w", err)
+		}
+		if resolvedID == "" && len(remote) > 0 {
+			resolvedID = remote[0].ChannelID
+		}
+
+		local, err := mirror.Scan(syncOutputDir)
+		if err != nil {
+			return err
+		}
+
+		state, err := mirror.LoadState(syncOutputDir)
+		if err != nil {
+			return err
+		}
+		if state.ChannelID != "" && resolvedID != "" && state.ChannelID != resolvedID {
+			fmt.Printf("⚠️  %s was last synced from channel %s, not %s\n", syncOutputDir, state.ChannelID, resolvedID)
+		}
+
+		now := time.Now().UTC()
+		lookup := func(ids []string) ([]youtube.Video, error) {
+			return ytService.GetVideos(cmd.Context(), ids)
+		}
+		result, err := state.Diff(resolvedID, local, remote, lookup, now)
+		if err != nil {
+			return fmt.Errorf("failed to compare channel with %s: %w", syncOutputDir, err)
+		}
+
+		fmt.Printf("🔄 Channel has %d videos, %d already downloaded, %d new\n\n", len(remote), len(local), len(result.New))
+		printSyncEvents(result.Events)
+
+		if syncDryRun {
+			for _, video := range result.New {
+				fmt.Printf("➕ would download: %s (%s)\n", video.Title, video.ID)
+			}
+			if syncPrune != mirror.PruneNone {
+				for _, video := range result.Removed {
+					fmt.Printf("🗑️  would %s files of: %s (%s)\n", syncPrune, video.Title, video.ID)
+				}
+			}
+			return nil
+		}
+
+		var downloadErr error
+		added := 0
+		if len(result.New) > 0 {
+			if err := os.MkdirAll(syncOutputDir, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+
+			downloadErr = dl.DownloadVideos(cmd.Context(), result.New)
+
+			// Only videos whose files landed count as added; failures are retried next sync
+			local, err = mirror.Scan(syncOutputDir)
+			if err != nil {
+				return err
+			}
+			var downloaded []youtube.Video
+			for _, video := range result.New {
+				if _, ok := local[video.ID]; ok {
+					downloaded = append(downloaded, video)
+				}
+			}
+			added = len(state.MarkAdded(downloaded, now))
+		}
+
+		pruned := 0
+		if len(result.Removed) > 0 && syncPrune != mirror.PruneNone {
+			// An empty listing is far more likely an API problem than a wiped channel
+			if cmd.Context().Err() != nil {
+				fmt.Println("⚠️  Sync was interrupted; not pruning anything")
+			} else if len(remote) == 0 {
+				fmt.Println("⚠️  The channel listing is empty; not pruning anything")
+			} else if pruned, err = mirror.Prune(syncOutputDir, result.Removed, syncPrune); err != nil {
+				return err
+			}
+		}
+
+		state.Finish(now)
+		if err := state.Save(); err != nil {
+			return err
+		}
+
+		printSyncSummary(result, added, pruned)
+		if downloadErr != nil {
+			return fmt.Errorf("download failed: %w", downloadErr)
+		}
+		return nil
+	},
+}
+
+// printSyncEvents lists the changes detected on the channel
+func printSyncEvents(events []mirror.Event) {
+	for _, event := range events {
+		switch event.Kind {
+		case mirror.EventRemoved:
+			fmt.Printf("🗑️  removed from channel: %s (%s)\n", event.Title, event.VideoID)
+		case mirror.EventPrivated:
+			fmt.Printf("🔒 made private: %s (%s)\n", event.Title, event.VideoID)
+		case mirror.EventTitleChanged:
+			fmt.Printf("✏️  retitled: %q → %q (%s)\n", event.From, event.To, event.VideoID)
+		}
+	}
+	if len(events) > 0 {
+		fmt.Println()
+	}
+}
+
+// printSyncSummary prints the counts of a finished sync
+func printSyncSummary(result *mirror.Result, added, pruned int) {
+	counts := make(map[string]int)
+	for _, event := range result.Events {
+		counts[event.Kind]++
+	}
+
+	fmt.Println("\n📋 Sync summary")
+	fmt.Printf("   ➕ Added:         %d\n", added)
+	if failed := len(result.New) - added; failed > 0 {
+		fmt.Printf("   ❌ Not added:     %d\n", failed)
+	}
+	fmt.Printf("   ✏️  Retitled:      %d\n", counts[mirror.EventTitleChanged])
+	fmt.Printf("   🔒 Made private:  %d\n", counts[mirror.EventPrivated])
+	fmt.Printf("   🗑️  Removed:       %d (newly detected: %d)\n", len(result.Removed), counts[mirror.EventRemoved])
+	if pruned > 0 {
+		fmt.Printf("   📦 Pruned files:  %d (%s)\n", pruned, syncPrune)
+	}
+}
+
+func init() {
+	syncCmd.Flags().StringVarP(&syncChannelID, "channel", "c", "", "YouTube channel ID, @handle or URL (leave empty to use authenticated user's channel)")
+	syncCmd.Flags().StringVarP(&syncOutputDir, "output", "o", "./downloads", "Output directory to mirror into")
+	syncCmd.Flags().StringVarP(&syncQuality, "quality", "q", "best", "Video quality (best, 1080p, 720p, 480p)")
+	syncCmd.Flags().IntVarP(&syncConcurrent, "concurrent", "j", 3, "Number of concurrent downloads")
+	syncCmd.Flags().StringVar(&syncPrune, "prune", mirror.PruneNone, "What to do with files of removed videos: none, move (to _removed/), delete")
+	addFormatFlags(syncCmd, &syncFormat)
+	addLayoutFlags(syncCmd, &syncLayout)
+	addProgressFlags(syncCmd, &syncProgress)
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show what would change without downloading, pruning or saving state")
+}
@@ -0,0 +1,337 @@
+package archive
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/fsutil"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+)
+
+// Entry sources
+const (
+	SourceYeeTrap = "yeetrap"
+	SourceYtDlp   = "yt-dlp"
+)
+
+// File is a file written for an archived video
+type File struct {
+	// Path is relative to the output directory
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Entry records a backed-up video
+type Entry struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	// Profile is the format profile of the copy; empty for the main copy
+	// downloaded with a quality or --format
+	Profile      string    `json:"profile,omitempty"`
+	Format       string    `json:"format,omitempty"`
+	Files        []File    `json:"files,omitempty"`
+	Size         int64     `json:"size"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+	Source       string    `json:"source"`
+}
+
+// archiveFile is the on-disk format of the archive
+type archiveFile struct {
+	Version int      `json:"version"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Archive is the persistent record of videos backed up into an output
+// directory, kept at <output>/.yeetrap/archive.json
+type Archive struct {
+	outputDir string
+	path      string
+
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+// Path returns the archive file location for an output directory
+func Path(outputDir string) string {
+	return filepath.Join(outputDir, constants.StateDirName, constants.ArchiveFile)
+}
+
+// Open loads the archive of an output directory, starting an empty one if
+// none exists yet
+func Open(outputDir string) (*Archive, error) {
+	a := &Archive{
+		outputDir: outputDir,
+		path:      Path(outputDir),
+		entries:   make(map[string]*Entry),
+	}
+
+	data, err := os.ReadFile(a.path)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to read download archive")
+	}
+
+	var file archiveFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.WrapFile(err, "unable to parse download archive").
+			WithContext("path", a.path)
+	}
+
+	for _, entry := range file.Entries {
+		a.entries[key(entry.ID, entry.Profile)] = entry
+	}
+
+	logger.Debug("Loaded download archive with %d entries from %s", len(a.entries), a.path)
+	return a, nil
+}
+
+// Location returns the path of the archive file
+func (a *Archive) Location() string {
+	return a.path
+}
+
+// key identifies the entry of a video's copy in a format profile
+func key(id, profile string) string {
+	if profile == "" {
+		return id
+	}
+	return id + "/" + profile
+}
+
+// Get returns the entry of a video's main copy, if archived
+func (a *Archive) Get(id string) (*Entry, bool) {
+	return a.GetProfile(id, "")
+}
+
+// GetProfile returns the entry of a video's copy in a format profile, if
+// archived
+func (a *Archive) GetProfile(id, profile string) (*Entry, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.entries[key(id, profile)]
+	return entry, ok
+}
+
+// Has reports whether a video's main copy is archived and its recorded files
+// are still present. Entries imported from yt-dlp archives carry no files and
+// are trusted as they are.
+func (a *Archive) Has(id string) bool {
+	return a.HasProfile(id, "")
+}
+
+// HasProfile is Has for a video's copy in a format profile
+func (a *Archive) HasProfile(id, profile string) bool {
+	entry, ok := a.GetProfile(id, profile)
+	if !ok {
+		return false
+	}
+
+	for _, file := range entry.Files {
+		if _, err := os.Stat(filepath.Join(a.outputDir, file.Path)); err != nil {
+			logger.Debug("Archived file %s of %s is missing", file.Path, id)
+			return false
+		}
+	}
+	return true
+}
+
+// Entries returns all entries, most recent first
+func (a *Archive) Entries() []*Entry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries := make([]*Entry, 0, len(a.entries))
+	for _, entry := range a.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].DownloadedAt.Equal(entries[j].DownloadedAt) {
+			return entries[i].DownloadedAt.After(entries[j].DownloadedAt)
+		}
+		return key(entries[i].ID, entries[i].Profile) < key(entries[j].ID, entries[j].Profile)
+	})
+	return entries
+}
+
+// Record adds or replaces the entry of a video and saves the archive right
+// away, so an interrupted run keeps what it finished
+func (a *Archive) Record(entry *Entry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entries[key(entry.ID, entry.Profile)] = entry
+	return a.saveLocked()
+}
+
+// RecordFiles archives a downloaded video, checksumming the files that start
+// with basePath (the video's output path without extension)
+func (a *Archive) RecordFiles(id, title, profile, format, basePath string) (*Entry, error) {
+	files, err := a.collectFiles(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{
+		ID:           id,
+		Title:        title,
+		Profile:      profile,
+		Format:       format,
+		Files:        files,
+		DownloadedAt: time.Now().UTC(),
+		Source:       SourceYeeTrap,
+	}
+	for _, file := range files {
+		entry.Size += file.Size
+	}
+
+	if err := a.Record(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// collectFiles finds and checksums the files written for a base path
+func (a *Archive) collectFiles(basePath string) ([]File, error) {
+	dir := filepath.Dir(basePath)
+	prefix := filepath.Base(basePath) + "."
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to read output directory")
+	}
+
+	var files []File
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if !dirEntry.Type().IsRegular() || !strings.HasPrefix(name, prefix) || isPartialFile(name) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		size, sum, err := checksum(path)
+		if err != nil {
+			return nil, err
+		}
+
+		rel, err := filepath.Rel(a.outputDir, path)
+		if err != nil {
+			rel = path
+		}
+		files = append(files, File{Path: filepath.ToSlash(rel), Size: size, SHA256: sum})
+	}
+
+	return files, nil
+}
+
+// ImportYtDlp adds the videos listed in a yt-dlp --download-archive file,
+// whose lines look like "youtube <video ID>". Videos already archived and
+// entries of other sites are skipped.
+func (a *Archive) ImportYtDlp(path string) (imported, skipped int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, errors.WrapFile(err, "unable to open yt-dlp archive")
+	}
+	defer file.Close()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now().UTC()
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 2 || !strings.EqualFold(fields[0], "youtube") || validation.ValidateVideoID(fields[1]) != nil {
+			logger.Debug("Skipping yt-dlp archive line %d: %q", line, scanner.Text())
+			skipped++
+			continue
+		}
+
+		if _, ok := a.entries[fields[1]]; ok {
+			skipped++
+			continue
+		}
+
+		a.entries[fields[1]] = &Entry{ID: fields[1], DownloadedAt: now, Source: SourceYtDlp}
+		imported++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, 0, errors.WrapFile(err, "unable to read yt-dlp archive")
+	}
+
+	if imported > 0 {
+		if err := a.saveLocked(); err != nil {
+			return 0, 0, err
+		}
+	}
+	return imported, skipped, nil
+}
+
+// saveLocked writes the archive; the caller holds a.mu
+func (a *Archive) saveLocked() error {
+	file := archiveFile{Version: 1, Entries: make([]*Entry, 0, len(a.entries))}
+	for _, entry := range a.entries {
+		file.Entries = append(file.Entries, entry)
+	}
+	sort.Slice(file.Entries, func(i, j int) bool {
+		return key(file.Entries[i].ID, file.Entries[i].Profile) < key(file.Entries[j].ID, file.Entries[j].Profile)
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return errors.WrapFile(err, "unable to encode download archive")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return errors.WrapFile(err, "unable to create archive directory")
+	}
+
+	if err := fsutil.WriteFileAtomic(a.path, data, 0644); err != nil {
+		return errors.WrapFile(err, "unable to write download archive")
+	}
+	return nil
+}
+
+// checksum returns the size and SHA-256 of a file
+func checksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", errors.WrapFile(err, fmt.Sprintf("unable to open %s", path))
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", errors.WrapFile(err, fmt.Sprintf("unable to read %s", path))
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isPartialFile reports whether name is an unfinished yt-dlp download
+func isPartialFile(name string) bool {
+	return strings.HasSuffix(name, ".part") || strings.HasSuffix(name, ".ytdl") || strings.Contains(name, ".part-Frag")
+}
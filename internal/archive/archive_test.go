@@ -0,0 +1,156 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportYtDlp(t *testing.T) {
+	dir := t.TempDir()
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := a.Record(&Entry{ID: "aaaaaaaaaaa", Title: "Already archived", Source: SourceYeeTrap}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	source := filepath.Join(t.TempDir(), "archive.txt")
+	writeFile(t, source, strings.Join([]string{
+		"youtube dQw4w9WgXcQ",
+		"",
+		"YouTube 9bZkp7q19f0",
+		"vimeo 123456789",
+		"youtube tooShort",
+		"youtube",
+		"youtube dQw4w9WgXcQ extra",
+		"youtube aaaaaaaaaaa",
+		"youtube dQw4w9WgXcQ",
+	}, "\n"))
+
+	imported, skipped, err := a.ImportYtDlp(source)
+	if err != nil {
+		t.Fatalf("ImportYtDlp() error = %v", err)
+	}
+	if imported != 2 || skipped != 6 {
+		t.Errorf("ImportYtDlp() = %d imported, %d skipped; want 2, 6", imported, skipped)
+	}
+
+	// The imported entries are saved, and the existing one is untouched
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	var ids []string
+	for _, entry := range reopened.Entries() {
+		ids = append(ids, entry.ID)
+	}
+	if want := []string{"9bZkp7q19f0", "dQw4w9WgXcQ", "aaaaaaaaaaa"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("entries = %v, want %v", ids, want)
+	}
+	if entry, _ := reopened.Get("dQw4w9WgXcQ"); entry.Source != SourceYtDlp || len(entry.Files) != 0 {
+		t.Errorf("imported entry = %+v, want a yt-dlp entry without files", entry)
+	}
+	if entry, _ := reopened.Get("aaaaaaaaaaa"); entry.Source != SourceYeeTrap || entry.Title != "Already archived" {
+		t.Errorf("existing entry = %+v, want it unchanged", entry)
+	}
+	if !reopened.Has("dQw4w9WgXcQ") {
+		t.Error("Has() = false for an imported entry without files")
+	}
+
+	if _, _, err := a.ImportYtDlp(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("ImportYtDlp() of a missing file succeeded")
+	}
+}
+
+func TestCollectFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "Channel", "Title [dQw4w9WgXcQ]")
+	for name, content := range map[string]string{
+		"Title [dQw4w9WgXcQ].mp4":                 "video",
+		"Title [dQw4w9WgXcQ].en.vtt":              "subtitles",
+		"Title [dQw4w9WgXcQ].f137.mp4.part":       "partial",
+		"Title [dQw4w9WgXcQ].mp4.ytdl":            "state",
+		"Title [dQw4w9WgXcQ].f137.mp4.part-Frag3": "fragment",
+		"Title [dQw4w9WgXcQ]2.mp4":                "other video",
+		"Other [9bZkp7q19f0].mp4":                 "other video",
+	} {
+		writeFile(t, filepath.Join(dir, "Channel", name), content)
+	}
+	if err := os.Mkdir(base+".d", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	files, err := a.collectFiles(base)
+	if err != nil {
+		t.Fatalf("collectFiles() error = %v", err)
+	}
+
+	want := []File{
+		{Path: "Channel/Title [dQw4w9WgXcQ].en.vtt", Size: 9, SHA256: sha256Hex("subtitles")},
+		{Path: "Channel/Title [dQw4w9WgXcQ].mp4", Size: 5, SHA256: sha256Hex("video")},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("collectFiles() = %+v, want %+v", files, want)
+	}
+}
+
+func TestHasProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Title [dQw4w9WgXcQ].mp4"), "video")
+	writeFile(t, filepath.Join(dir, "Title [dQw4w9WgXcQ].en.vtt"), "subtitles")
+	writeFile(t, filepath.Join(dir, "Title [dQw4w9WgXcQ].m4a"), "audio")
+
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := a.RecordFiles("dQw4w9WgXcQ", "Title", "", "", filepath.Join(dir, "Title [dQw4w9WgXcQ]")); err != nil {
+		t.Fatalf("RecordFiles() error = %v", err)
+	}
+	if err := a.Record(&Entry{ID: "dQw4w9WgXcQ", Profile: "audio", Files: []File{{Path: "Title [dQw4w9WgXcQ].m4a"}}}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	if !a.Has("dQw4w9WgXcQ") || !a.HasProfile("dQw4w9WgXcQ", "audio") {
+		t.Fatal("Has() = false with all files present")
+	}
+	if a.HasProfile("dQw4w9WgXcQ", "mobile") || a.Has("9bZkp7q19f0") {
+		t.Error("Has() = true for a copy that was never archived")
+	}
+
+	// A missing subtitle file invalidates the main copy, not the audio one
+	if err := os.Remove(filepath.Join(dir, "Title [dQw4w9WgXcQ].en.vtt")); err != nil {
+		t.Fatal(err)
+	}
+	if a.Has("dQw4w9WgXcQ") {
+		t.Error("Has() = true with an archived file missing")
+	}
+	if !a.HasProfile("dQw4w9WgXcQ", "audio") {
+		t.Error("HasProfile() = false for a profile whose files are present")
+	}
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
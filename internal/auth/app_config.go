@@ -1,33 +1,174 @@
 package auth
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 
 	"github.com/AlienFacepalm/YeeTrap/internal/constants"
 	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/fsutil"
 	"github.com/AlienFacepalm/YeeTrap/internal/logger"
 )
 
+// clientSecretFile is the layout of a client secret file downloaded from Google Cloud Console
+type clientSecretFile struct {
+	Installed *clientSecret `json:"installed"`
+	Web       *clientSecret `json:"web"`
+	Type      string        `json:"type"`
+}
+
+// clientSecret is the OAuth2 client section of a client secret file
+type clientSecret struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	ProjectID    string   `json:"project_id"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+// desktopClientGuidance explains how to obtain the right kind of client
+const desktopClientGuidance = "In Google Cloud Console go to 'APIs & Services' → 'Credentials' → 'Create Credentials' → 'OAuth client ID', " +
+	"choose Application type 'Desktop app' and download its JSON"
+
 // AppConfig represents the OAuth2 app configuration
 type AppConfig struct {
 	ClientID     string `json:"client_id"`
 	ClientSecret string `json:"client_secret"`
 	ProjectID    string `json:"project_id"`
 	AppName      string `json:"app_name"`
-	RedirectURI  string `json:"redirect_uri"`
+	// RedirectURI is the client's loopback redirect URI; empty for 'TVs and
+	// Limited Input devices' clients, which only support the device flow
+	RedirectURI string `json:"redirect_uri"`
+
+	redirectURIs []string
 }
 
 // GetAppInfo returns information about the OAuth2 app setup
 func GetAppInfo() *AppConfig {
 	return &AppConfig{
 		AppName:     constants.AppName,
-		RedirectURI: "http://localhost:8080/callback",
+		RedirectURI: fmt.Sprintf("http://%s:<ephemeral port>%s", constants.OAuthLoopbackHost, constants.OAuthCallbackPath),
+	}
+}
+
+// ParseCredentials validates the contents of a client secret file and returns
+// the OAuth2 app configuration it describes. Clients without redirect URIs are
+// accepted for the device flow; see CheckBrowserFlow.
+func ParseCredentials(data []byte) (*AppConfig, error) {
+	var file clientSecretFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.WrapValidation(err, "credentials file is not valid JSON").
+			WithDetails(desktopClientGuidance)
+	}
+
+	switch {
+	case file.Type == "service_account":
+		return nil, errors.NewValidationError("credentials file is a service account key, not an OAuth client").
+			WithDetails("Service accounts cannot access YouTube channels. " + desktopClientGuidance)
+	case file.Web != nil:
+		return nil, errors.NewValidationError("credentials file is for a 'Web application' client").
+			WithDetails("YeeTrap needs a 'Desktop app' client so it can receive the login on a local port. " + desktopClientGuidance)
+	case file.Installed == nil:
+		return nil, errors.NewValidationError("credentials file does not contain an OAuth client").
+			WithDetails(desktopClientGuidance)
+	}
+
+	client := file.Installed
+	if client.ClientID == "" {
+		return nil, errors.NewValidationError("credentials file is missing client_id").
+			WithDetails("Re-download the JSON from Google Cloud Console")
+	}
+	if client.ClientSecret == "" {
+		return nil, errors.NewValidationError("credentials file is missing client_secret").
+			WithDetails("Re-download the JSON from Google Cloud Console")
+	}
+
+	redirectURI := ""
+	for _, uri := range client.RedirectURIs {
+		if isLoopbackURI(uri) {
+			redirectURI = uri
+			break
+		}
+	}
+
+	return &AppConfig{
+		ClientID:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+		ProjectID:    client.ProjectID,
+		AppName:      constants.AppName,
+		RedirectURI:  redirectURI,
+		redirectURIs: client.RedirectURIs,
+	}, nil
+}
+
+// CheckBrowserFlow checks that the client can receive the login on a local
+// port, as the browser and --no-browser flows need
+func (a *AppConfig) CheckBrowserFlow() error {
+	if a.RedirectURI != "" {
+		return nil
 	}
+	if len(a.redirectURIs) == 0 {
+		return errors.NewValidationError("credentials file has no redirect URIs").
+			WithDetails("Desktop app clients list 'http://localhost' as redirect URI; clients for TVs and Limited Input devices only work with --device. " + desktopClientGuidance)
+	}
+	return errors.NewValidationError("credentials file has no loopback redirect URI").
+		WithContext("redirect_uris", a.redirectURIs).
+		WithDetails("Desktop app clients list 'http://localhost' as redirect URI. " + desktopClientGuidance)
 }
 
-// ValidateCredentials checks if the credentials file exists and is valid
-func ValidateCredentials() error {
+// InstallCredentials validates a client secret file and copies it into the
+// active profile with 0600 permissions, returning the installed path. Unless
+// device is set, the client must also support the browser flow.
+func InstallCredentials(srcPath string, device bool) (string, *AppConfig, error) {
+	logger.Info("Installing credentials from %s", srcPath)
+
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", nil, errors.WrapFile(err, "unable to read credentials file")
+	}
+
+	app, err := ParseCredentials(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if !device {
+		if err := app.CheckBrowserFlow(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	credPath, err := constants.GetCredentialsPath()
+	if err != nil {
+		return "", nil, errors.WrapConfig(err, "failed to get credentials path")
+	}
+
+	if err := fsutil.WriteFileAtomic(credPath, data, 0600); err != nil {
+		return "", nil, errors.WrapFile(err, "unable to install credentials file")
+	}
+
+	logger.Info("Credentials installed to %s", credPath)
+	return credPath, app, nil
+}
+
+// isLoopbackURI reports whether uri is an http redirect to the local machine
+func isLoopbackURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateCredentials checks if the credentials file exists and is valid for
+// the browser flows, or with device for the device flow
+func ValidateCredentials(device bool) error {
 	logger.Debug("Validating credentials file")
 	
 	credPath, err := constants.GetCredentialsPath()
@@ -41,11 +182,21 @@ func ValidateCredentials() error {
 	}
 
 	// Try to read and parse the credentials
-	_, err = os.ReadFile(credPath)
+	data, err := os.ReadFile(credPath)
 	if err != nil {
 		return errors.WrapFile(err, "unable to read credentials file")
 	}
 
+	app, err := ParseCredentials(data)
+	if err != nil {
+		return err
+	}
+	if !device {
+		if err := app.CheckBrowserFlow(); err != nil {
+			return err
+		}
+	}
+
 	logger.Debug("Credentials file validation successful")
 	return nil
 }
@@ -97,7 +248,8 @@ func PrintSetupInstructions() {
 	fmt.Println("   - Your browser should open automatically")
 	fmt.Println("   - Login with your Google/YouTube account")
 	fmt.Println("   - Grant permissions to YeeTrap")
-	fmt.Println("   - Copy the authorization code back to terminal")
+	fmt.Println("   - The browser confirms success and the terminal continues automatically")
+	fmt.Println("   - No browser on this machine? Run: yeetrap auth --no-browser")
 	fmt.Println()
 	fmt.Println("🎉 That's it! You're ready to use YeeTrap!")
 }
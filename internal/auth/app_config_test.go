@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+const (
+	desktopClient = `{"installed":{"client_id":"id.apps.googleusercontent.com","project_id":"yeetrap","client_secret":"secret","redirect_uris":["http://localhost"]}}`
+	deviceClient  = `{"installed":{"client_id":"id.apps.googleusercontent.com","project_id":"yeetrap","client_secret":"secret"}}`
+	oobClient     = `{"installed":{"client_id":"id.apps.googleusercontent.com","client_secret":"secret","redirect_uris":["urn:ietf:wg:oauth:2.0:oob"]}}`
+	webClient     = `{"web":{"client_id":"id.apps.googleusercontent.com","client_secret":"secret","redirect_uris":["https://example.com/callback"]}}`
+	serviceKey    = `{"type":"service_account","project_id":"yeetrap","private_key_id":"abc","client_email":"bot@yeetrap.iam.gserviceaccount.com"}`
+)
+
+func TestParseCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+		browser bool
+	}{
+		{name: "desktop", data: desktopClient, browser: true},
+		{name: "tv and limited input device", data: deviceClient},
+		{name: "out of band redirect", data: oobClient},
+		{name: "web", data: webClient, wantErr: true},
+		{name: "service account", data: serviceKey, wantErr: true},
+		{name: "missing client_id", data: `{"installed":{"client_secret":"secret"}}`, wantErr: true},
+		{name: "missing client_secret", data: `{"installed":{"client_id":"id"}}`, wantErr: true},
+		{name: "no client", data: `{}`, wantErr: true},
+		{name: "not JSON", data: `client_id=id`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := ParseCredentials([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCredentials() succeeded, want error")
+				}
+				if errors.GetErrorType(err) != errors.ErrorTypeValidation {
+					t.Errorf("ParseCredentials() error type = %q, want %q", errors.GetErrorType(err), errors.ErrorTypeValidation)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCredentials() error = %v", err)
+			}
+			if app.ClientID == "" || app.ClientSecret == "" {
+				t.Errorf("ParseCredentials() = %+v, want client ID and secret", app)
+			}
+
+			err = app.CheckBrowserFlow()
+			if tt.browser && err != nil {
+				t.Errorf("CheckBrowserFlow() error = %v, want nil", err)
+			}
+			if !tt.browser && err == nil {
+				t.Errorf("CheckBrowserFlow() succeeded for a client without loopback redirect URI")
+			}
+		})
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		browserErr bool
+		deviceErr  bool
+	}{
+		{name: "desktop", data: desktopClient},
+		{name: "tv and limited input device", data: deviceClient, browserErr: true},
+		{name: "web", data: webClient, browserErr: true, deviceErr: true},
+		{name: "service account", data: serviceKey, browserErr: true, deviceErr: true},
+		{name: "missing field", data: `{"installed":{"client_id":"id"}}`, browserErr: true, deviceErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			credPath, err := constants.GetCredentialsPath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Dir(credPath), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(credPath, []byte(tt.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ValidateCredentials(false); (err != nil) != tt.browserErr {
+				t.Errorf("ValidateCredentials(false) error = %v, want error %v", err, tt.browserErr)
+			}
+			if err := ValidateCredentials(true); (err != nil) != tt.deviceErr {
+				t.Errorf("ValidateCredentials(true) error = %v, want error %v", err, tt.deviceErr)
+			}
+		})
+	}
+}
+
+func TestValidateCredentialsMissingFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := ValidateCredentials(true); errors.GetErrorType(err) != errors.ErrorTypeConfig {
+		t.Errorf("ValidateCredentials() error = %v, want a config error", err)
+	}
+}
+
+func TestInstallCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		device  bool
+		wantErr bool
+	}{
+		{name: "desktop", data: desktopClient},
+		{name: "desktop for the device flow", data: desktopClient, device: true},
+		{name: "tv and limited input device", data: deviceClient, wantErr: true},
+		{name: "tv and limited input device for the device flow", data: deviceClient, device: true},
+		{name: "web", data: webClient, device: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			src := filepath.Join(t.TempDir(), "client_secret.json")
+			if err := os.WriteFile(src, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			credPath, err := constants.GetCredentialsPath()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = InstallCredentials(src, tt.device)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InstallCredentials() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			info, statErr := os.Stat(credPath)
+			if tt.wantErr {
+				if statErr == nil {
+					t.Error("rejected credentials were installed")
+				}
+				return
+			}
+			if statErr != nil {
+				t.Fatalf("credentials not installed: %v", statErr)
+			}
+			if perm := info.Mode().Perm(); perm != 0600 {
+				t.Errorf("installed credentials have mode %o, want 600", perm)
+			}
+		})
+	}
+}
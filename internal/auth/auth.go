@@ -2,18 +2,19 @@ package auth
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 
+	appconfig "github.com/AlienFacepalm/YeeTrap/internal/config"
 	"github.com/AlienFacepalm/YeeTrap/internal/constants"
 	"github.com/AlienFacepalm/YeeTrap/internal/errors"
 	"github.com/AlienFacepalm/YeeTrap/internal/logger"
@@ -21,16 +22,21 @@ import (
 	"github.com/AlienFacepalm/YeeTrap/internal/validation"
 )
 
-// Use constants from the constants package
+// includeGrantedScopes asks Google to keep previously granted scopes, so
+// requesting an extra feature's scope is an incremental re-consent
+var includeGrantedScopes = oauth2.SetAuthURLParam("include_granted_scopes", "true")
 
 // Authenticator handles YouTube OAuth2 authentication
 type Authenticator struct {
-	config    *oauth2.Config
-	tokenPath string
+	config      *oauth2.Config
+	store       TokenStore
+	revokeURL   string
+	openBrowser func(url string) error
 }
 
-// NewAuthenticator creates a new authenticator
-func NewAuthenticator() (*Authenticator, error) {
+// NewAuthenticator creates a new authenticator requesting the scopes needed
+// by the given features (read access is always requested)
+func NewAuthenticator(features ...Feature) (*Authenticator, error) {
 	logger.Debug("Creating new authenticator")
 	
 	credPath, err := constants.GetCredentialsPath()
@@ -44,72 +50,147 @@ func NewAuthenticator() (*Authenticator, error) {
 			WithDetails(fmt.Sprintf("Please create %s with your OAuth2 credentials from Google Cloud Console", credPath))
 	}
 
-	config, err := google.ConfigFromJSON(b, constants.YouTubeReadonlyScope)
+	config, err := google.ConfigFromJSON(b, ScopesFor(features...)...)
 	if err != nil {
 		return nil, errors.WrapConfig(err, "unable to parse client secret file to config")
 	}
 
-	tokenPath, err := constants.GetTokenPath()
+	// Client secret files carry no device endpoint; use Google's default
+	if config.Endpoint.DeviceAuthURL == "" {
+		config.Endpoint.DeviceAuthURL = google.Endpoint.DeviceAuthURL
+	}
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return nil, errors.WrapConfig(err, "failed to load profile configuration")
+	}
+
+	store, err := NewTokenStore(cfg)
 	if err != nil {
-		return nil, errors.WrapConfig(err, "failed to get token path")
+		return nil, err
 	}
 
 	logger.Debug("Authenticator created successfully")
+	return NewAuthenticatorWithConfig(config, store), nil
+}
+
+// NewAuthenticatorWithConfig creates an authenticator from an explicit OAuth2
+// configuration and token store, e.g. one pointing at a local stand-in token endpoint
+func NewAuthenticatorWithConfig(config *oauth2.Config, store TokenStore) *Authenticator {
+	revokeURL := constants.OAuthRevokeURL
+	if override := os.Getenv(constants.OAuthRevokeURLEnvVar); override != "" {
+		revokeURL = override
+	}
+
 	return &Authenticator{
-		config:    config,
-		tokenPath: tokenPath,
-	}, nil
+		config:      config,
+		store:       store,
+		revokeURL:   revokeURL,
+		openBrowser: openBrowser,
+	}
 }
 
-// Authenticate performs the OAuth2 flow with automatic browser opening
+// Authenticate performs the OAuth2 flow with automatic browser opening.
+// The authorization code is captured by a short-lived loopback listener and
+// the request is protected with PKCE and a random state value.
 func (a *Authenticator) Authenticate() error {
 	logger.Info("Starting YouTube authentication")
-	
-	// Generate a random state for security
-	state := fmt.Sprintf("yeetrap-%d", time.Now().Unix())
-	authURL := a.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
-	
+
+	state, err := newState()
+	if err != nil {
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	server, err := newLoopbackServer(state)
+	if err != nil {
+		return err
+	}
+	defer server.close()
+
+	config := *a.config
+	config.RedirectURL = server.redirectURL
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce,
+		oauth2.S256ChallengeOption(verifier), includeGrantedScopes)
+
 	fmt.Println("🔐 Starting YouTube authentication...")
 	fmt.Println("📱 Opening browser for Google OAuth2 login...")
-	
+
 	// Try to open browser automatically
-	if err := openBrowser(authURL); err != nil {
+	if err := a.openBrowser(authURL); err != nil {
 		logger.Warn("Could not open browser automatically: %v", err)
 		fmt.Printf("⚠️  Could not open browser automatically: %v\n", err)
-		fmt.Printf("🌐 Please open this URL in your browser:\n%v\n\n", authURL)
+		fmt.Printf("🌐 Please open this URL in a browser on this machine:\n%v\n\n", authURL)
 	} else {
 		logger.Info("Browser opened successfully")
 		fmt.Printf("🌐 Browser opened to: %v\n\n", authURL)
 	}
-	
+
+	fmt.Println("⏳ Waiting for you to log in and grant permissions in the browser...")
+
+	authCode, err := server.wait(constants.OAuthCallbackTimeout)
+	if err != nil {
+		return err
+	}
+
+	return a.exchangeAndSave(&config, authCode, verifier)
+}
+
+// AuthenticateManual performs the OAuth2 flow without a local listener.
+// The user opens the URL themselves and pastes the authorization code (or the
+// full redirect URL) back into the terminal.
+func (a *Authenticator) AuthenticateManual() error {
+	logger.Info("Starting manual YouTube authentication")
+
+	state, err := newState()
+	if err != nil {
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL := a.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce,
+		oauth2.S256ChallengeOption(verifier), includeGrantedScopes)
+
+	fmt.Println("🔐 Starting YouTube authentication...")
+	fmt.Printf("🌐 Please open this URL in your browser:\n%v\n\n", authURL)
 	fmt.Println("📋 After logging in and granting permissions:")
 	fmt.Println("   1. You'll be redirected to a page that may show an error (this is normal)")
-	fmt.Println("   2. Copy the authorization code from the URL or page")
+	fmt.Println("   2. Copy the full URL from the address bar, or just the 'code' value")
 	fmt.Println("   3. Paste it below when prompted")
 	fmt.Println()
 	fmt.Print("🔑 Enter the authorization code: ")
 
-	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
+	var input string
+	if _, err := fmt.Scan(&input); err != nil {
 		return errors.WrapAuth(err, "unable to read authorization code")
 	}
 
+	authCode, err := parsePastedCode(input, state)
+	if err != nil {
+		return err
+	}
+
+	return a.exchangeAndSave(a.config, authCode, verifier)
+}
+
+// exchangeAndSave trades an authorization code for a token and stores it
+func (a *Authenticator) exchangeAndSave(config *oauth2.Config, authCode, verifier string) error {
 	// Validate and clean up the auth code
 	authCode, err := validation.ValidateAndSanitizeInput(authCode, "auth_code")
 	if err != nil {
 		return err
 	}
-	
+
 	fmt.Println("🔄 Exchanging authorization code for access token...")
-	
+
 	// Use retry logic for token exchange
 	var tok *oauth2.Token
 	err = retry.RetryAPIOperation(func() error {
 		var err error
-		tok, err = a.config.Exchange(context.TODO(), authCode)
+		tok, err = config.Exchange(context.TODO(), authCode, oauth2.VerifierOption(verifier))
 		return err
 	})
-	
+
 	if err != nil {
 		return errors.WrapAuth(err, "unable to retrieve token from web")
 	}
@@ -118,7 +199,37 @@ func (a *Authenticator) Authenticate() error {
 	return a.saveToken(tok)
 }
 
-// GetClient returns an authenticated HTTP client
+// parsePastedCode extracts the authorization code from user input, which may be
+// either the bare code or the full redirect URL
+func parsePastedCode(input, state string) (string, error) {
+	input = strings.TrimSpace(input)
+	if !strings.Contains(input, "code=") {
+		return input, nil
+	}
+
+	query := input
+	if u, err := url.Parse(input); err == nil && u.RawQuery != "" {
+		query = u.RawQuery
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", errors.WrapValidation(err, "unable to parse pasted redirect URL")
+	}
+
+	// A pasted redirect URL must come from this attempt
+	if !validState(values.Get("state"), state) {
+		return "", errors.NewAuthError("pasted redirect URL has an invalid state").
+			WithDetails("Make sure you pasted the URL from this authentication attempt")
+	}
+
+	return values.Get("code"), nil
+}
+
+// GetClient returns an authenticated HTTP client.
+// Tokens refreshed by the client are written back to the token store. If the
+// stored token lacks a scope the requested features need, an error for which
+// MissingScopes returns the missing scopes is returned.
 func (a *Authenticator) GetClient() (*http.Client, error) {
 	logger.Debug("Getting authenticated HTTP client")
 	
@@ -128,48 +239,91 @@ func (a *Authenticator) GetClient() (*http.Client, error) {
 			WithDetails("Please run 'yeetrap auth' first")
 	}
 
+	ctx := context.Background()
+	source := newPersistingTokenSource(a.config.TokenSource(ctx, tok), tok, a.writeToken)
+
+	if err := a.checkScopes(source); err != nil {
+		return nil, err
+	}
+
 	logger.Debug("HTTP client created successfully")
-	return a.config.Client(context.Background(), tok), nil
+	return oauth2.NewClient(ctx, source), nil
 }
 
-// saveToken saves a token to a file path
-func (a *Authenticator) saveToken(token *oauth2.Token) error {
-	logger.Debug("Saving authentication token")
-	
-	// Ensure the directory exists
-	if err := os.MkdirAll(filepath.Dir(a.tokenPath), 0700); err != nil {
-		return errors.WrapFile(err, "unable to create token directory")
+// checkScopes verifies that the token covers the configured scopes. Tokens
+// saved without scope information are looked up once via tokeninfo.
+func (a *Authenticator) checkScopes(source *persistingTokenSource) error {
+	granted := source.Scopes()
+
+	if len(granted) == 0 {
+		tok, err := source.Token()
+		if err != nil {
+			return errors.WrapAuth(err, "unable to refresh token").
+				WithDetails("Please run 'yeetrap auth' again")
+		}
+
+		granted, err = fetchTokenScopes(tok.AccessToken)
+		if err != nil {
+			// Not fatal: the API will still reject calls the token cannot make
+			logger.Warn("Unable to determine granted scopes: %v", err)
+			return nil
+		}
+
+		if err := source.RecordScopes(tok, granted); err != nil {
+			logger.Warn("Unable to persist granted scopes: %v", err)
+		}
 	}
 
-	f, err := os.OpenFile(a.tokenPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if missing := missingScopes(a.config.Scopes, granted); len(missing) > 0 {
+		logger.Warn("Token is missing scopes: %v", missing)
+		return newInsufficientScopesError(missing)
+	}
+
+	return nil
+}
+
+// Status reports on the locally stored token
+func (a *Authenticator) Status() (*TokenStatus, error) {
+	tok, err := a.loadToken()
 	if err != nil {
-		return errors.WrapFile(err, "unable to create token file")
+		return nil, errors.WrapAuth(err, "unable to load token").
+			WithDetails("Please run 'yeetrap auth' first")
 	}
-	defer f.Close()
+
+	return &TokenStatus{
+		Location:        a.store.Location(),
+		Expiry:          tok.Expiry,
+		Expired:         !tok.Expiry.IsZero() && time.Now().After(tok.Expiry),
+		Scopes:          tokenScopes(tok),
+		HasRefreshToken: tok.RefreshToken != "",
+	}, nil
+}
+
+// saveToken saves a token to the token store
+func (a *Authenticator) saveToken(token *oauth2.Token) error {
+	logger.Debug("Saving authentication token")
 	
-	if err := json.NewEncoder(f).Encode(token); err != nil {
-		return errors.WrapFile(err, "unable to encode token")
+	if err := a.writeToken(token); err != nil {
+		return err
 	}
 	
 	logger.Info("Authentication token saved successfully")
-	fmt.Printf("✅ Authentication token saved to: %s\n", a.tokenPath)
+	fmt.Printf("✅ Authentication token saved to: %s\n", a.store.Location())
 	return nil
 }
 
-// loadToken retrieves a token from a local file
+// writeToken saves a token without reporting to the user
+func (a *Authenticator) writeToken(token *oauth2.Token) error {
+	return a.store.Save(token)
+}
+
+// loadToken retrieves a token from the token store
 func (a *Authenticator) loadToken() (*oauth2.Token, error) {
 	logger.Debug("Loading authentication token")
 	
-	f, err := os.Open(a.tokenPath)
-	if err != nil {
-		return nil, errors.WrapFile(err, "unable to open token file")
-	}
-	defer f.Close()
-	
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
+	tok, err := a.store.Load()
 	if err != nil {
-		return nil, errors.WrapFile(err, "unable to decode token")
+		return nil, err
 	}
 	
 	logger.Debug("Authentication token loaded successfully")
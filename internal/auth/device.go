@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/retry"
+)
+
+// AuthenticateDevice performs the OAuth2 device authorization grant.
+// It prints a verification URL and user code, then polls the token endpoint
+// until the user approves the request from any other device.
+func (a *Authenticator) AuthenticateDevice() error {
+	logger.Info("Starting YouTube device authentication")
+
+	if a.config.Endpoint.DeviceAuthURL == "" {
+		return errors.NewConfigError("OAuth2 endpoint does not support device authorization")
+	}
+
+	var resp *oauth2.DeviceAuthResponse
+	err := retry.RetryAPIOperation(func() error {
+		var err error
+		resp, err = a.config.DeviceAuth(context.TODO(), oauth2.AccessTypeOffline)
+		return err
+	})
+	if err != nil {
+		return errors.WrapAuth(err, "unable to start device authorization").
+			WithDetails("Device authorization requires an OAuth client of type 'TVs and Limited Input devices'")
+	}
+
+	verificationURL := resp.VerificationURI
+	if resp.VerificationURIComplete != "" {
+		verificationURL = resp.VerificationURIComplete
+	}
+
+	fmt.Println("🔐 Starting YouTube device authentication...")
+	fmt.Println("📱 On any device with a browser:")
+	fmt.Printf("   1. Open: %s\n", verificationURL)
+	fmt.Printf("   2. Enter the code: %s\n", resp.UserCode)
+	fmt.Println("   3. Log in and grant permissions to YeeTrap")
+	fmt.Println()
+	if !resp.Expiry.IsZero() {
+		fmt.Printf("⏳ Waiting for approval (code expires in %v)...\n", time.Until(resp.Expiry).Round(time.Second))
+	} else {
+		fmt.Println("⏳ Waiting for approval...")
+	}
+
+	ctx := context.Background()
+	if !resp.Expiry.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, resp.Expiry)
+		defer cancel()
+	}
+
+	// DeviceAccessToken honours the polling interval and slow_down responses
+	tok, err := a.config.DeviceAccessToken(ctx, resp)
+	if err != nil {
+		// DeviceAccessToken sets its own deadline at the same expiry, which
+		// may fire before ours
+		if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+			return errors.NewAuthError("device code expired before it was approved").
+				WithDetails("Run 'yeetrap auth --device' again to get a new code")
+		}
+		return errors.WrapAuth(err, "device authorization was not completed")
+	}
+
+	fmt.Println("💾 Saving authentication token...")
+	return a.saveToken(tok)
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// deviceServer is a stand-in for Google's device and token endpoints. Each
+// poll of the token endpoint answers with the next of its responses, an OAuth2
+// error code or "" for a token.
+type deviceServer struct {
+	expiresIn int
+
+	mu        sync.Mutex
+	responses []string
+	polls     int
+}
+
+func (s *deviceServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/device":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_url": "https://www.google.com/device",
+			"expires_in":       s.expiresIn,
+			"interval":         1,
+		})
+	case "/token":
+		r.ParseForm()
+		if r.Form.Get("device_code") != "device-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		s.mu.Lock()
+		response := "authorization_pending"
+		if s.polls < len(s.responses) {
+			response = s.responses[s.polls]
+		}
+		s.polls++
+		s.mu.Unlock()
+
+		if response != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": response})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"scope":         strings.Join(ScopesFor(), " "),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestAuthenticateDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string
+		expiresIn int
+		wantErr   string
+		wantPolls int
+	}{
+		{name: "pending then approved", responses: []string{"authorization_pending", "authorization_pending", ""}, wantPolls: 3},
+		{name: "slow down", responses: []string{"slow_down", ""}, wantPolls: 2},
+		{name: "expired token", responses: []string{"authorization_pending", "expired_token"}, wantErr: "device authorization was not completed", wantPolls: 2},
+		{name: "access denied", responses: []string{"access_denied"}, wantErr: "device authorization was not completed", wantPolls: 1},
+		{name: "code expires while pending", expiresIn: 2, wantErr: "device code expired"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stub := &deviceServer{responses: tt.responses, expiresIn: tt.expiresIn}
+			if stub.expiresIn == 0 {
+				stub.expiresIn = 60
+			}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+			authenticator := NewAuthenticatorWithConfig(&oauth2.Config{
+				ClientID:     "client-id",
+				ClientSecret: "client-secret",
+				Scopes:       ScopesFor(),
+				Endpoint: oauth2.Endpoint{
+					DeviceAuthURL: server.URL + "/device",
+					TokenURL:      server.URL + "/token",
+					AuthStyle:     oauth2.AuthStyleInParams,
+				},
+			}, store)
+
+			err := authenticator.AuthenticateDevice()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AuthenticateDevice() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := store.Load(); err == nil {
+					t.Errorf("token was saved after a failed authorization")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("AuthenticateDevice() error = %v", err)
+				}
+				tok, err := store.Load()
+				if err != nil {
+					t.Fatalf("token was not saved: %v", err)
+				}
+				if tok.AccessToken != "access-token" || tok.RefreshToken != "refresh-token" {
+					t.Errorf("saved token = %+v, want the issued access and refresh token", tok)
+				}
+			}
+
+			if tt.wantPolls > 0 && stub.polls != tt.wantPolls {
+				t.Errorf("token endpoint polled %d times, want %d", stub.polls, tt.wantPolls)
+			}
+		})
+	}
+}
+
+func TestAuthenticateDeviceWithoutEndpoint(t *testing.T) {
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+	authenticator := NewAuthenticatorWithConfig(&oauth2.Config{ClientID: "client-id"}, store)
+
+	if err := authenticator.AuthenticateDevice(); err == nil {
+		t.Fatal("AuthenticateDevice() succeeded without a device authorization endpoint")
+	}
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+)
+
+// successPage is shown in the browser once the authorization code has been captured
+const successPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>YeeTrap - Authentication complete</title></head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 4em;">
+<h1>&#x2705; YeeTrap is authorized</h1>
+<p>You can close this window and return to the terminal.</p>
+</body>
+</html>`
+
+// failurePage is shown in the browser when the callback could not be accepted
+const failurePage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>YeeTrap - Authentication failed</title></head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 4em;">
+<h1>&#x274C; YeeTrap authentication failed</h1>
+<p>%s</p>
+<p>Return to the terminal for details.</p>
+</body>
+</html>`
+
+// callbackResult carries the outcome of a single loopback callback
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// loopbackServer is a short-lived HTTP listener that receives the OAuth2 redirect
+type loopbackServer struct {
+	listener    net.Listener
+	server      *http.Server
+	state       string
+	redirectURL string
+	results     chan callbackResult
+}
+
+// newLoopbackServer starts listening on an ephemeral loopback port
+func newLoopbackServer(state string) (*loopbackServer, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(constants.OAuthLoopbackHost, "0"))
+	if err != nil {
+		return nil, errors.WrapNetwork(err, "unable to start local callback listener")
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	ls := &loopbackServer{
+		listener:    listener,
+		state:       state,
+		redirectURL: fmt.Sprintf("http://%s:%d%s", constants.OAuthLoopbackHost, port, constants.OAuthCallbackPath),
+		results:     make(chan callbackResult, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(constants.OAuthCallbackPath, ls.handleCallback)
+	ls.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := ls.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Warn("Callback listener stopped: %v", err)
+		}
+	}()
+
+	logger.Debug("Callback listener started on %s", ls.redirectURL)
+	return ls, nil
+}
+
+// handleCallback validates the redirect and hands the authorization code to the waiting flow
+func (ls *loopbackServer) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	// A request with the wrong state did not come from this attempt's
+	// redirect; reject it but keep waiting, so it cannot abort the login
+	if !validState(query.Get("state"), ls.state) {
+		logger.Warn("Ignoring authorization callback with an invalid state")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, failurePage, "The callback did not originate from this authentication attempt.")
+		return
+	}
+
+	var result callbackResult
+	switch {
+	case query.Get("error") != "":
+		result.err = errors.NewAuthError(fmt.Sprintf("authorization was denied: %s", query.Get("error")))
+	case query.Get("code") == "":
+		result.err = errors.NewAuthError("authorization callback is missing the code")
+	default:
+		result.code = query.Get("code")
+	}
+
+	if result.err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, failurePage, html.EscapeString(errors.FormatError(result.err)))
+	} else {
+		fmt.Fprint(w, successPage)
+	}
+
+	// Only the first callback counts; later requests (e.g. a refresh) are ignored
+	select {
+	case ls.results <- result:
+	default:
+	}
+}
+
+// wait blocks until a callback arrives or the timeout expires
+func (ls *loopbackServer) wait(timeout time.Duration) (string, error) {
+	select {
+	case result := <-ls.results:
+		return result.code, result.err
+	case <-time.After(timeout):
+		return "", errors.NewAuthError("timed out waiting for the browser callback").
+			WithDetails("Re-run 'yeetrap auth', or use 'yeetrap auth --no-browser' to paste the code manually")
+	}
+}
+
+// close shuts down the listener
+func (ls *loopbackServer) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := ls.server.Shutdown(ctx); err != nil {
+		logger.Debug("Callback listener shutdown: %v", err)
+	}
+}
+
+// newState returns a cryptographically random OAuth2 state value
+func newState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.WrapAuth(err, "unable to generate OAuth2 state")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// validState compares the returned state against the expected one in constant time
+func validState(got, want string) bool {
+	if got == "" || want == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
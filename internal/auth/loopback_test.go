@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+const testAuthCode = "4/0AbCdEfGhIjKlMnOp"
+
+// codeServer is a stand-in for Google's token endpoint, checking the
+// authorization code and its PKCE verifier
+type codeServer struct {
+	mu          sync.Mutex
+	challenge   string
+	redirectURI string
+	exchanges   int
+}
+
+func (s *codeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exchanges++
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	switch {
+	case r.Form.Get("grant_type") != "authorization_code" || r.Form.Get("code") != testAuthCode,
+		r.Form.Get("redirect_uri") != s.redirectURI,
+		base64.RawURLEncoding.EncodeToString(sum[:]) != s.challenge:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token":  "access-token",
+		"refresh_token": "refresh-token",
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+		"scope":         strings.Join(ScopesFor(), " "),
+	})
+}
+
+// callback requests the loopback redirect with the given query parameters
+func callback(t *testing.T, redirectURI string, params url.Values) int {
+	t.Helper()
+	resp, err := http.Get(redirectURI + "?" + params.Encode())
+	if err != nil {
+		t.Errorf("callback error = %v", err)
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name string
+		// browser plays the user's browser, given the authorization URL's
+		// query, and returns the callback statuses it saw
+		browser    func(t *testing.T, auth url.Values) []int
+		wantStatus []int
+		wantErr    string
+	}{
+		{
+			name: "approved",
+			browser: func(t *testing.T, auth url.Values) []int {
+				return []int{callback(t, auth.Get("redirect_uri"), url.Values{"state": {auth.Get("state")}, "code": {testAuthCode}})}
+			},
+			wantStatus: []int{http.StatusOK},
+		},
+		{
+			name: "forged callbacks are ignored",
+			browser: func(t *testing.T, auth url.Values) []int {
+				redirect := auth.Get("redirect_uri")
+				return []int{
+					callback(t, redirect, url.Values{"state": {"forged"}, "code": {"4/attacker-code"}}),
+					callback(t, redirect, url.Values{"code": {"4/attacker-code"}}),
+					callback(t, redirect, url.Values{"error": {"access_denied"}}),
+					callback(t, redirect, url.Values{"state": {auth.Get("state")}, "code": {testAuthCode}}),
+				}
+			},
+			wantStatus: []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest, http.StatusOK},
+		},
+		{
+			name: "denied",
+			browser: func(t *testing.T, auth url.Values) []int {
+				return []int{callback(t, auth.Get("redirect_uri"), url.Values{"state": {auth.Get("state")}, "error": {"access_denied"}})}
+			},
+			wantStatus: []int{http.StatusBadRequest},
+			wantErr:    "authorization was denied: access_denied",
+		},
+		{
+			name: "missing code",
+			browser: func(t *testing.T, auth url.Values) []int {
+				return []int{callback(t, auth.Get("redirect_uri"), url.Values{"state": {auth.Get("state")}})}
+			},
+			wantStatus: []int{http.StatusBadRequest},
+			wantErr:    "missing the code",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &codeServer{}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+			authenticator := NewAuthenticatorWithConfig(&oauth2.Config{
+				ClientID:     "client-id",
+				ClientSecret: "client-secret",
+				Scopes:       ScopesFor(),
+				Endpoint: oauth2.Endpoint{
+					AuthURL:   "https://accounts.example.com/auth",
+					TokenURL:  server.URL,
+					AuthStyle: oauth2.AuthStyleInParams,
+				},
+			}, store)
+
+			var statuses []int
+			done := make(chan struct{})
+			authenticator.openBrowser = func(authURL string) error {
+				u, err := url.Parse(authURL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				auth := u.Query()
+				if auth.Get("code_challenge_method") != "S256" || auth.Get("code_challenge") == "" {
+					t.Errorf("authorization URL %s does not use PKCE", authURL)
+				}
+				if !strings.HasPrefix(auth.Get("redirect_uri"), "http://127.0.0.1:") {
+					t.Errorf("redirect URI = %q, want the loopback listener", auth.Get("redirect_uri"))
+				}
+				stub.mu.Lock()
+				stub.challenge = auth.Get("code_challenge")
+				stub.redirectURI = auth.Get("redirect_uri")
+				stub.mu.Unlock()
+
+				go func() {
+					defer close(done)
+					statuses = tt.browser(t, auth)
+				}()
+				return nil
+			}
+
+			err := authenticator.Authenticate()
+			<-done
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %q", err, tt.wantErr)
+				}
+				if stub.exchanges != 0 {
+					t.Errorf("code exchanged %d times after a failed callback", stub.exchanges)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Authenticate() error = %v", err)
+				}
+				tok, err := store.Load()
+				if err != nil {
+					t.Fatalf("token was not saved: %v", err)
+				}
+				if tok.AccessToken != "access-token" {
+					t.Errorf("saved token = %+v, want the issued token", tok)
+				}
+			}
+
+			if len(statuses) != len(tt.wantStatus) {
+				t.Fatalf("callback statuses = %v, want %v", statuses, tt.wantStatus)
+			}
+			for i := range statuses {
+				if statuses[i] != tt.wantStatus[i] {
+					t.Errorf("callback statuses = %v, want %v", statuses, tt.wantStatus)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParsePastedCode(t *testing.T) {
+	const state = "expected-state"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare code", input: "  " + testAuthCode + "\n", want: testAuthCode},
+		{name: "redirect URL", input: "http://localhost/?state=expected-state&code=" + url.QueryEscape(testAuthCode) + "&scope=x", want: testAuthCode},
+		{name: "query only", input: "state=expected-state&code=" + url.QueryEscape(testAuthCode), want: testAuthCode},
+		{name: "wrong state", input: "http://localhost/?state=other&code=" + url.QueryEscape(testAuthCode), wantErr: true},
+		{name: "no state", input: "http://localhost/?code=" + url.QueryEscape(testAuthCode), wantErr: true},
+		{name: "empty state", input: "http://localhost/?state=&code=" + url.QueryEscape(testAuthCode), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePastedCode(tt.input, state)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePastedCode() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parsePastedCode() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+)
+
+// RevokeResult reports the outcome of revoking the stored grant
+type RevokeResult struct {
+	// RemoteRevoked is true when the revocation endpoint accepted the token
+	RemoteRevoked bool
+	// AlreadyInvalid is true when the endpoint reported the token as already revoked or expired
+	AlreadyInvalid bool
+	// RemoteErr holds the reason remote revocation failed, if it did
+	RemoteErr error
+	// LocalDeleted is true when the local token was removed
+	LocalDeleted bool
+}
+
+// Revoke revokes the stored grant at the authorization server and deletes the
+// local token. The local token is deleted even when remote revocation fails;
+// the result reports both outcomes separately.
+func (a *Authenticator) Revoke() (*RevokeResult, error) {
+	logger.Info("Revoking authentication token")
+
+	tok, err := a.loadToken()
+	if err != nil {
+		return nil, errors.WrapAuth(err, "unable to load token").
+			WithDetails("There is no stored token to revoke")
+	}
+
+	// Revoking the refresh token also invalidates its access tokens
+	token := tok.RefreshToken
+	if token == "" {
+		token = tok.AccessToken
+	}
+
+	result := &RevokeResult{}
+	if err := a.revokeRemote(token); err != nil {
+		if ytErr, ok := err.(*errors.YeeTrapError); ok && ytErr.Context["oauth_error"] == "invalid_token" {
+			result.AlreadyInvalid = true
+		} else {
+			result.RemoteErr = err
+		}
+		logger.Warn("Remote revocation failed: %v", err)
+	} else {
+		result.RemoteRevoked = true
+		logger.Info("Remote revocation succeeded")
+	}
+
+	if err := a.store.Delete(); err != nil {
+		return result, err
+	}
+	result.LocalDeleted = true
+
+	return result, nil
+}
+
+// revokeRemote calls the OAuth2 token revocation endpoint
+func (a *Authenticator) revokeRemote(token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.WrapNetwork(err, "unable to build revocation request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.WrapNetwork(err, "unable to reach revocation endpoint")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var oauthErr struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	json.Unmarshal(body, &oauthErr)
+
+	ytErr := errors.NewAuthError(fmt.Sprintf("revocation endpoint returned %s", resp.Status)).
+		WithContext("status", resp.StatusCode)
+	if oauthErr.Error != "" {
+		ytErr = ytErr.WithContext("oauth_error", oauthErr.Error).WithDetails(oauthErr.ErrorDescription)
+	}
+	return ytErr
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestRevoke(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		unreachable   bool
+		wantRevoked   bool
+		wantInvalid   bool
+		wantRemoteErr bool
+	}{
+		{name: "revoked", status: http.StatusOK, wantRevoked: true},
+		{name: "already invalid", status: http.StatusBadRequest, body: `{"error":"invalid_token","error_description":"Token expired or revoked"}`, wantInvalid: true},
+		{name: "server error", status: http.StatusServiceUnavailable, body: `{"error":"backend_error"}`, wantRemoteErr: true},
+		{name: "network error", unreachable: true, wantRemoteErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var revoked string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				revoked = r.Form.Get("token")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			revokeURL := server.URL
+			if tt.unreachable {
+				server.Close()
+			}
+
+			store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+			if err := store.Save(&oauth2.Token{AccessToken: "access-token", RefreshToken: "refresh-token"}); err != nil {
+				t.Fatal(err)
+			}
+			authenticator := NewAuthenticatorWithConfig(&oauth2.Config{}, store)
+			authenticator.revokeURL = revokeURL
+
+			result, err := authenticator.Revoke()
+			if err != nil {
+				t.Fatalf("Revoke() error = %v", err)
+			}
+
+			if result.RemoteRevoked != tt.wantRevoked || result.AlreadyInvalid != tt.wantInvalid || (result.RemoteErr != nil) != tt.wantRemoteErr {
+				t.Errorf("Revoke() = %+v, want revoked %v, already invalid %v, remote error %v",
+					result, tt.wantRevoked, tt.wantInvalid, tt.wantRemoteErr)
+			}
+			if !tt.unreachable && revoked != "refresh-token" {
+				t.Errorf("revoked token %q, want the refresh token", revoked)
+			}
+			if !result.LocalDeleted {
+				t.Errorf("Revoke() did not report the local token as deleted")
+			}
+			if _, err := store.Load(); err == nil {
+				t.Errorf("local token still exists after Revoke()")
+			}
+		})
+	}
+}
+
+func TestRevokeWithoutToken(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	authenticator := NewAuthenticatorWithConfig(&oauth2.Config{}, NewFileTokenStore(filepath.Join(t.TempDir(), "token.json")))
+	authenticator.revokeURL = server.URL
+
+	result, err := authenticator.Revoke()
+	if err == nil {
+		t.Fatalf("Revoke() = %+v, want an error without a stored token", result)
+	}
+	if called {
+		t.Errorf("Revoke() called the revocation endpoint without a stored token")
+	}
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+)
+
+// Feature is a YeeTrap capability that needs particular OAuth2 scopes
+type Feature string
+
+const (
+	// FeatureRead covers listing and downloading videos
+	FeatureRead Feature = "read"
+	// FeatureCaptions covers downloading owner caption tracks through the Captions API
+	FeatureCaptions Feature = "captions"
+)
+
+// featureScopes maps each feature to the scopes it requires
+var featureScopes = map[Feature][]string{
+	FeatureRead:     {constants.YouTubeReadonlyScope},
+	FeatureCaptions: {constants.YouTubeForceSSLScope},
+}
+
+// impliedScopes lists broader scopes that also satisfy a narrower one
+var impliedScopes = map[string][]string{
+	constants.YouTubeReadonlyScope: {constants.YouTubeScope, constants.YouTubeForceSSLScope},
+	constants.YouTubeForceSSLScope: {constants.YouTubeScope},
+}
+
+// contextKeyMissingScopes is the error context key listing scopes a token lacks
+const contextKeyMissingScopes = "missing_scopes"
+
+// Features returns the names of all known features
+func Features() []string {
+	names := make([]string, 0, len(featureScopes))
+	for feature := range featureScopes {
+		names = append(names, string(feature))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFeature converts a feature name into a Feature
+func ParseFeature(name string) (Feature, error) {
+	feature := Feature(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := featureScopes[feature]; !ok {
+		return "", errors.NewValidationError(fmt.Sprintf("unknown feature: %s", name)).
+			WithDetails(fmt.Sprintf("Supported features: %s", strings.Join(Features(), ", ")))
+	}
+	return feature, nil
+}
+
+// ScopesFor returns the scopes needed by the given features. Read access is
+// always included since every command lists videos.
+func ScopesFor(features ...Feature) []string {
+	seen := make(map[string]bool)
+	var scopes []string
+
+	for _, feature := range append([]Feature{FeatureRead}, features...) {
+		for _, scope := range featureScopes[feature] {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return scopes
+}
+
+// missingScopes returns the required scopes not covered by the granted ones
+func missingScopes(required, granted []string) []string {
+	have := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		have[scope] = true
+	}
+
+	var missing []string
+	for _, scope := range required {
+		if have[scope] {
+			continue
+		}
+
+		covered := false
+		for _, broader := range impliedScopes[scope] {
+			if have[broader] {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
+
+// newInsufficientScopesError reports scopes the stored token lacks
+func newInsufficientScopesError(missing []string) *errors.YeeTrapError {
+	return errors.NewAuthError("stored token is missing required permissions").
+		WithContext(contextKeyMissingScopes, missing).
+		WithDetails("Run 'yeetrap auth' to grant the additional permissions")
+}
+
+// MissingScopes returns the scopes reported by an insufficient-scopes error,
+// or nil if err is not one
+func MissingScopes(err error) []string {
+	ytErr, ok := err.(*errors.YeeTrapError)
+	if !ok || ytErr.Type != errors.ErrorTypeAuth {
+		return nil
+	}
+
+	missing, _ := ytErr.Context[contextKeyMissingScopes].([]string)
+	return missing
+}
+
+// fetchTokenScopes asks the tokeninfo endpoint which scopes an access token carries
+func fetchTokenScopes(accessToken string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	endpoint := constants.OAuthTokenInfoURL + "?" + url.Values{"access_token": {accessToken}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.WrapNetwork(err, "unable to build tokeninfo request")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.WrapNetwork(err, "unable to reach tokeninfo endpoint")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.NewAuthError(fmt.Sprintf("tokeninfo endpoint returned %s", resp.Status))
+	}
+
+	var info struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, errors.WrapAPI(err, "unable to decode tokeninfo response")
+	}
+
+	logger.Debug("Token scopes from tokeninfo: %s", info.Scope)
+	return strings.Fields(info.Scope), nil
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/oauth2"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/config"
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/fsutil"
+)
+
+// TokenStore persists the OAuth2 token of a profile
+type TokenStore interface {
+	// Load returns the stored token
+	Load() (*oauth2.Token, error)
+	// Save replaces the stored token
+	Save(tok *oauth2.Token) error
+	// Delete removes the stored token; deleting a missing token is not an error
+	Delete() error
+	// Location describes where the token is kept, for display
+	Location() string
+}
+
+// NewTokenStore returns the token store selected in the profile configuration
+func NewTokenStore(cfg *config.Config) (TokenStore, error) {
+	profileDir, err := constants.GetProfileDir()
+	if err != nil {
+		return nil, errors.WrapConfig(err, "failed to get profile directory")
+	}
+
+	switch cfg.TokenStore {
+	case "", constants.TokenStoreFile:
+		return NewFileTokenStore(filepath.Join(profileDir, constants.TokenFile)), nil
+	case constants.TokenStoreEncrypted:
+		return NewEncryptedFileTokenStore(filepath.Join(profileDir, constants.EncryptedTokenFile), PromptPassphrase), nil
+	case constants.TokenStoreHelper:
+		if cfg.TokenHelper == "" {
+			return nil, errors.NewConfigError("token_helper must be set when token_store is \"helper\"")
+		}
+		return NewHelperTokenStore(cfg.TokenHelper, constants.GetActiveProfile()), nil
+	default:
+		return nil, errors.NewConfigError(fmt.Sprintf("unknown token store: %s", cfg.TokenStore)).
+			WithDetails(fmt.Sprintf("Supported token stores: %s, %s, %s",
+				constants.TokenStoreFile, constants.TokenStoreEncrypted, constants.TokenStoreHelper))
+	}
+}
+
+// FileTokenStore keeps the token as plaintext JSON in a 0600 file
+type FileTokenStore struct {
+	path string
+}
+
+// NewFileTokenStore creates a plaintext file token store
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load implements TokenStore
+func (s *FileTokenStore) Load() (*oauth2.Token, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to open token file")
+	}
+
+	tok, err := decodeToken(data)
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to decode token")
+	}
+	return tok, nil
+}
+
+// Save implements TokenStore. The file is replaced atomically, so concurrent
+// runs never observe a partially written token.
+func (s *FileTokenStore) Save(tok *oauth2.Token) error {
+	data, err := encodeToken(tok)
+	if err != nil {
+		return errors.WrapFile(err, "unable to encode token")
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data, 0600); err != nil {
+		return errors.WrapFile(err, "unable to write token file")
+	}
+	return nil
+}
+
+// Delete implements TokenStore
+func (s *FileTokenStore) Delete() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return errors.WrapFile(err, "unable to delete token file")
+	}
+	return nil
+}
+
+// Location implements TokenStore
+func (s *FileTokenStore) Location() string {
+	return s.path
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+	"golang.org/x/term"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/fsutil"
+)
+
+// scrypt parameters for deriving the token encryption key
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	scryptSalt   = 16
+)
+
+// encryptedToken is the on-disk format of an encrypted token file
+type encryptedToken struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// PassphraseFunc supplies the passphrase protecting an encrypted token. With
+// confirm, a new passphrase is being chosen and should be asked for twice.
+type PassphraseFunc func(confirm bool) (string, error)
+
+// EncryptedFileTokenStore keeps the token in a file encrypted with AES-GCM,
+// using a key derived from a passphrase with scrypt
+type EncryptedFileTokenStore struct {
+	path       string
+	passphrase PassphraseFunc
+
+	mu     sync.Mutex
+	cached string
+}
+
+// NewEncryptedFileTokenStore creates an encrypted file token store
+func NewEncryptedFileTokenStore(path string, passphrase PassphraseFunc) *EncryptedFileTokenStore {
+	return &EncryptedFileTokenStore{path: path, passphrase: passphrase}
+}
+
+// Load implements TokenStore
+func (s *EncryptedFileTokenStore) Load() (*oauth2.Token, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to open encrypted token file")
+	}
+
+	var env encryptedToken
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, errors.WrapFile(err, "unable to decode encrypted token file")
+	}
+	if env.Version != 1 || env.KDF != "scrypt" {
+		return nil, errors.NewFileError(fmt.Sprintf("unsupported encrypted token format (version %d, kdf %q)", env.Version, env.KDF))
+	}
+
+	passphrase, err := s.getPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), env.Salt, env.N, env.R, env.P, scryptKeyLen)
+	if err != nil {
+		return nil, errors.WrapAuth(err, "unable to derive token encryption key")
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.NewAuthError("unable to decrypt token").
+			WithDetails("The passphrase is wrong or the token file is corrupted")
+	}
+
+	tok, err := decodeToken(plaintext)
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to decode token")
+	}
+	return tok, nil
+}
+
+// Save implements TokenStore
+func (s *EncryptedFileTokenStore) Save(tok *oauth2.Token) error {
+	plaintext, err := encodeToken(tok)
+	if err != nil {
+		return errors.WrapFile(err, "unable to encode token")
+	}
+
+	// The first token chooses the passphrase; a typo would lock it away
+	_, statErr := os.Stat(s.path)
+	passphrase, err := s.getPassphrase(os.IsNotExist(statErr))
+	if err != nil {
+		return err
+	}
+
+	env := encryptedToken{Version: 1, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP}
+	env.Salt = make([]byte, scryptSalt)
+	if _, err := rand.Read(env.Salt); err != nil {
+		return errors.WrapAuth(err, "unable to generate salt")
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), env.Salt, env.N, env.R, env.P, scryptKeyLen)
+	if err != nil {
+		return errors.WrapAuth(err, "unable to derive token encryption key")
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return errors.WrapAuth(err, "unable to generate nonce")
+	}
+	env.Ciphertext = gcm.Seal(nil, env.Nonce, plaintext, nil)
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return errors.WrapFile(err, "unable to encode encrypted token")
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data, 0600); err != nil {
+		return errors.WrapFile(err, "unable to write encrypted token file")
+	}
+	return nil
+}
+
+// Delete implements TokenStore
+func (s *EncryptedFileTokenStore) Delete() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return errors.WrapFile(err, "unable to delete encrypted token file")
+	}
+	return nil
+}
+
+// Location implements TokenStore
+func (s *EncryptedFileTokenStore) Location() string {
+	return s.path + " (encrypted)"
+}
+
+// getPassphrase asks for the passphrase once per run
+func (s *EncryptedFileTokenStore) getPassphrase(confirm bool) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != "" {
+		return s.cached, nil
+	}
+
+	passphrase, err := s.passphrase(confirm)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.NewValidationError("token passphrase cannot be empty")
+	}
+
+	s.cached = passphrase
+	return passphrase, nil
+}
+
+// PromptPassphrase reads the token passphrase from YEETRAP_TOKEN_PASSPHRASE,
+// or prompts for it without echo when running in a terminal. A new
+// passphrase is prompted for twice.
+func PromptPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(constants.TokenPassphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.NewAuthError("token passphrase required").
+			WithDetails(fmt.Sprintf("Set %s when running non-interactively", constants.TokenPassphraseEnvVar))
+	}
+
+	prompt := "🔑 Token passphrase: "
+	if confirm {
+		prompt = "🔑 New token passphrase: "
+	}
+	passphrase, err := readPassword(fd, prompt)
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+
+	repeated, err := readPassword(fd, "🔑 Repeat token passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if repeated != passphrase {
+		return "", errors.NewValidationError("token passphrases do not match").
+			WithDetails("Run the command again and enter the same passphrase twice")
+	}
+	return passphrase, nil
+}
+
+// readPassword prompts on stderr and reads a line without echo
+func readPassword(fd int, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", errors.WrapAuth(err, "unable to read token passphrase")
+	}
+	return string(passphrase), nil
+}
+
+// newGCM creates an AES-GCM AEAD for the given key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WrapAuth(err, "unable to initialize cipher")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WrapAuth(err, "unable to initialize cipher")
+	}
+	return gcm, nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+// passphrases returns a PassphraseFunc answering with the given passphrase
+// and recording whether confirmation was asked for
+func passphrases(passphrase string, confirms *[]bool) PassphraseFunc {
+	return func(confirm bool) (string, error) {
+		*confirms = append(*confirms, confirm)
+		return passphrase, nil
+	}
+}
+
+func TestEncryptedFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.enc.json")
+	tok := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour).Round(time.Second)}
+
+	var confirms []bool
+	store := NewEncryptedFileTokenStore(path, passphrases("correct horse", &confirms))
+	if err := store.Save(tok); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// The passphrase is chosen with the first token and asked for once per run
+	if err := store.Save(tok); err != nil {
+		t.Fatalf("second Save() error = %v", err)
+	}
+	if len(confirms) != 1 || !confirms[0] {
+		t.Errorf("passphrase prompts = %v, want one with confirmation", confirms)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "refresh") || strings.Contains(string(data), "access") {
+		t.Error("token file contains the token in plain text")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	// A new run asks again, without confirmation as the token exists
+	confirms = nil
+	got, err := NewEncryptedFileTokenStore(path, passphrases("correct horse", &confirms)).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.AccessToken != tok.AccessToken || got.RefreshToken != tok.RefreshToken || !got.Expiry.Equal(tok.Expiry) {
+		t.Errorf("Load() = %+v, want %+v", got, tok)
+	}
+	if len(confirms) != 1 || confirms[0] {
+		t.Errorf("passphrase prompts = %v, want one without confirmation", confirms)
+	}
+
+	confirms = nil
+	other := NewEncryptedFileTokenStore(path, passphrases("wrong horse", &confirms))
+	if err := other.Save(tok); err != nil {
+		t.Fatalf("Save() over an existing token error = %v", err)
+	}
+	if len(confirms) != 1 || confirms[0] {
+		t.Errorf("passphrase prompts replacing a token = %v, want one without confirmation", confirms)
+	}
+}
+
+func TestEncryptedFileTokenStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.enc.json")
+	var confirms []bool
+	if err := NewEncryptedFileTokenStore(path, passphrases("correct horse", &confirms)).Save(&oauth2.Token{AccessToken: "access"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewEncryptedFileTokenStore(path, passphrases("wrong horse", &confirms)).Load()
+	if err == nil {
+		t.Fatal("Load() with the wrong passphrase succeeded")
+	}
+	if errors.GetErrorType(err) != errors.ErrorTypeAuth {
+		t.Errorf("Load() error = %v, want an authentication error", err)
+	}
+
+	empty := NewEncryptedFileTokenStore(path, passphrases("", &confirms))
+	if _, err := empty.Load(); err == nil {
+		t.Error("Load() with an empty passphrase succeeded")
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/oauth2"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+)
+
+// Attribute values sent to credential helpers
+const (
+	helperProtocol = "oauth2"
+	helperHost     = "yeetrap"
+)
+
+// HelperTokenStore delegates token storage to an external program speaking a
+// git-credential-helper style protocol. The helper is invoked as
+//
+//	<helper> get|store|erase
+//
+// and receives key=value attributes on stdin, terminated by a blank line:
+//
+//	protocol=oauth2
+//	host=yeetrap
+//	username=<profile>
+//	password=<token JSON>   (store only)
+//
+// For "get", the helper prints the same kind of attributes on stdout and the
+// token is read from the password attribute.
+type HelperTokenStore struct {
+	command string
+	profile string
+}
+
+// NewHelperTokenStore creates a credential helper token store. Like git, the
+// command runs through the shell, so it may include quoted paths and
+// arguments, e.g. "vault-helper --mount yeetrap". On Windows it is split at
+// whitespace instead.
+func NewHelperTokenStore(command, profile string) *HelperTokenStore {
+	return &HelperTokenStore{command: command, profile: profile}
+}
+
+// Load implements TokenStore
+func (s *HelperTokenStore) Load() (*oauth2.Token, error) {
+	out, err := s.run("get", "")
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := parseHelperAttributes(out)
+	password := attrs["password"]
+	if password == "" {
+		return nil, errors.NewAuthError("credential helper returned no token").
+			WithContext("helper", s.command)
+	}
+
+	tok, err := decodeToken([]byte(password))
+	if err != nil {
+		return nil, errors.WrapAuth(err, "unable to decode token from credential helper")
+	}
+	return tok, nil
+}
+
+// Save implements TokenStore
+func (s *HelperTokenStore) Save(tok *oauth2.Token) error {
+	data, err := encodeToken(tok)
+	if err != nil {
+		return errors.WrapFile(err, "unable to encode token")
+	}
+
+	_, err = s.run("store", string(data))
+	return err
+}
+
+// Delete implements TokenStore
+func (s *HelperTokenStore) Delete() error {
+	_, err := s.run("erase", "")
+	return err
+}
+
+// Location implements TokenStore
+func (s *HelperTokenStore) Location() string {
+	return fmt.Sprintf("credential helper %q (username %s)", s.command, s.profile)
+}
+
+// run invokes the helper with an operation and the request attributes
+func (s *HelperTokenStore) run(operation, password string) ([]byte, error) {
+	if strings.TrimSpace(s.command) == "" {
+		return nil, errors.NewConfigError("credential helper command is empty")
+	}
+
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "protocol=%s\n", helperProtocol)
+	fmt.Fprintf(&input, "host=%s\n", helperHost)
+	fmt.Fprintf(&input, "username=%s\n", s.profile)
+	if password != "" {
+		fmt.Fprintf(&input, "password=%s\n", password)
+	}
+	input.WriteString("\n")
+
+	logger.Debug("Running credential helper: %s %s", s.command, operation)
+
+	var stdout, stderr bytes.Buffer
+	cmd := helperCommand(s.command, operation)
+	cmd.Stdin = &input
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, errors.WrapExternal(err, fmt.Sprintf("credential helper %s failed", operation)).
+			WithContext("helper", s.command).
+			WithDetails(strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// helperCommand builds the helper invocation. As git does, the command goes
+// through sh with the operation as a positional argument, so quoting works.
+func helperCommand(command, operation string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		fields := strings.Fields(command)
+		return exec.Command(fields[0], append(fields[1:], operation)...)
+	}
+	return exec.Command("sh", "-c", command+` "$@"`, command, operation)
+}
+
+// parseHelperAttributes parses key=value lines up to the first blank line
+func parseHelperAttributes(data []byte) map[string]string {
+	attrs := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			attrs[key] = value
+		}
+	}
+
+	return attrs
+}
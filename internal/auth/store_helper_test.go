@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// helperScript is a credential helper keeping the token in a file next to
+// it and logging its arguments; the operation follows one configured argument
+const helperScript = `#!/bin/sh
+dir=$(dirname "$0")
+echo "$1 $2" >> "$dir/calls"
+case "$2" in
+get)
+	[ -f "$dir/stored" ] || { echo "no token" >&2; exit 1; }
+	cat "$dir/stored"
+	;;
+store)
+	grep -e '^password=' > "$dir/stored"
+	;;
+erase)
+	rm -f "$dir/stored"
+	;;
+esac
+`
+
+func TestHelperTokenStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helpers run through sh")
+	}
+
+	// Quoted paths and arguments reach the helper intact
+	dir := filepath.Join(t.TempDir(), "my helpers")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "token helper.sh")
+	if err := os.WriteFile(script, []byte(helperScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	store := NewHelperTokenStore(`"`+script+`" 'my vault'`, "work")
+
+	if _, err := store.Load(); err == nil {
+		t.Fatal("Load() succeeded before a token was stored")
+	}
+
+	tok := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer"}
+	if err := store.Save(tok); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.AccessToken != "access" || got.RefreshToken != "refresh" {
+		t.Errorf("Load() = %+v, want the saved token", got)
+	}
+
+	if err := store.Delete(); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Load(); err == nil {
+		t.Error("Load() succeeded after Delete()")
+	}
+
+	calls, err := os.ReadFile(filepath.Join(dir, "calls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "my vault get\nmy vault store\nmy vault get\nmy vault erase\nmy vault get\n"
+	if string(calls) != want {
+		t.Errorf("helper calls = %q, want %q", calls, want)
+	}
+}
+
+func TestHelperTokenStoreEmptyCommand(t *testing.T) {
+	if _, err := NewHelperTokenStore("  ", "default").Load(); err == nil {
+		t.Error("Load() with an empty helper command succeeded")
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+)
+
+// storedToken is the on-disk token format. It embeds the oauth2 token so
+// existing token files remain readable, and records the granted scopes which
+// oauth2.Token does not serialize itself.
+type storedToken struct {
+	*oauth2.Token
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// TokenStatus describes the locally stored token
+type TokenStatus struct {
+	Location        string
+	Expiry          time.Time
+	Expired         bool
+	Scopes          []string
+	HasRefreshToken bool
+}
+
+// encodeToken serializes a token together with its granted scopes
+func encodeToken(tok *oauth2.Token) ([]byte, error) {
+	return json.Marshal(storedToken{
+		Token:  tok,
+		Scopes: tokenScopes(tok),
+	})
+}
+
+// decodeToken parses a stored token and re-attaches its granted scopes
+func decodeToken(data []byte) (*oauth2.Token, error) {
+	st := storedToken{Token: &oauth2.Token{}}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+
+	tok := st.Token
+	if len(st.Scopes) > 0 {
+		tok = tok.WithExtra(map[string]interface{}{
+			"scope": strings.Join(st.Scopes, " "),
+		})
+	}
+	return tok, nil
+}
+
+// tokenScopes returns the scopes granted with a token, if known
+func tokenScopes(tok *oauth2.Token) []string {
+	scope, _ := tok.Extra("scope").(string)
+	return strings.Fields(scope)
+}
+
+// persistingTokenSource writes every newly issued token back to storage so
+// refreshed access tokens (and rotated refresh tokens) survive between runs
+type persistingTokenSource struct {
+	mu     sync.Mutex
+	base   oauth2.TokenSource
+	save   func(*oauth2.Token) error
+	last   string
+	scopes []string
+}
+
+// newPersistingTokenSource wraps base, treating initial as already persisted
+func newPersistingTokenSource(base oauth2.TokenSource, initial *oauth2.Token, save func(*oauth2.Token) error) *persistingTokenSource {
+	return &persistingTokenSource{
+		base:   base,
+		save:   save,
+		last:   initial.AccessToken,
+		scopes: tokenScopes(initial),
+	}
+}
+
+// Token implements oauth2.TokenSource
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tok.AccessToken == s.last {
+		return tok, nil
+	}
+
+	// Refresh responses may omit the scope; keep what we knew before
+	toSave := tok
+	if len(tokenScopes(tok)) == 0 && len(s.scopes) > 0 {
+		toSave = tok.WithExtra(map[string]interface{}{
+			"scope": strings.Join(s.scopes, " "),
+		})
+	}
+
+	if err := s.save(toSave); err != nil {
+		// The in-memory token is still valid for this run
+		logger.Warn("Unable to persist refreshed token: %v", err)
+		return tok, nil
+	}
+
+	logger.Debug("Persisted refreshed authentication token")
+	s.last = tok.AccessToken
+	s.scopes = tokenScopes(toSave)
+	return tok, nil
+}
+
+// Scopes returns the scopes known to be granted to the current token
+func (s *persistingTokenSource) Scopes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scopes
+}
+
+// RecordScopes attaches the granted scopes to tok and persists it
+func (s *persistingTokenSource) RecordScopes(tok *oauth2.Token, scopes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	withScopes := tok.WithExtra(map[string]interface{}{
+		"scope": strings.Join(scopes, " "),
+	})
+	if err := s.save(withScopes); err != nil {
+		return err
+	}
+
+	s.last = tok.AccessToken
+	s.scopes = scopes
+	return nil
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// countingStore counts the tokens saved to a file token store
+type countingStore struct {
+	*FileTokenStore
+
+	mu    sync.Mutex
+	saves int
+}
+
+func (s *countingStore) Save(tok *oauth2.Token) error {
+	s.mu.Lock()
+	s.saves++
+	s.mu.Unlock()
+	return s.FileTokenStore.Save(tok)
+}
+
+func TestGetClientPersistsRefreshedToken(t *testing.T) {
+	var mu sync.Mutex
+	refreshes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-token" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			refreshes++
+			mu.Unlock()
+			// Refresh responses omit the scope
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "fresh-access-token",
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		case "/api":
+			if r.Header.Get("Authorization") != "Bearer fresh-access-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := &countingStore{FileTokenStore: NewFileTokenStore(path)}
+	expired := (&oauth2.Token{
+		AccessToken:  "stale-access-token",
+		RefreshToken: "refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(-time.Hour),
+	}).WithExtra(map[string]interface{}{"scope": strings.Join(ScopesFor(), " ")})
+	if err := store.FileTokenStore.Save(expired); err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator := NewAuthenticatorWithConfig(&oauth2.Config{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		Scopes:       ScopesFor(),
+		Endpoint:     oauth2.Endpoint{TokenURL: server.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+	}, store)
+
+	client, err := authenticator.GetClient()
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL + "/api")
+			if err != nil {
+				t.Errorf("request error = %v", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("request status = %d, want the refreshed token to be used", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshes != 1 {
+		t.Errorf("token refreshed %d times, want once", refreshes)
+	}
+	if store.saves != 1 {
+		t.Errorf("token saved %d times, want once", store.saves)
+	}
+
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.AccessToken != "fresh-access-token" || saved.RefreshToken != "refresh-token" {
+		t.Errorf("saved token = %+v, want the refreshed access token and the old refresh token", saved)
+	}
+	if got := tokenScopes(saved); strings.Join(got, " ") != strings.Join(ScopesFor(), " ") {
+		t.Errorf("saved scopes = %v, want the scopes known before the refresh", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
 )
 
 // Config holds the application configuration
@@ -13,17 +16,32 @@ type Config struct {
 	DefaultQuality   string `json:"default_quality"`
 	OutputDir        string `json:"output_dir"`
 	MaxConcurrent    int    `json:"max_concurrent"`
+	OutputTemplate   string `json:"output_template,omitempty"`
+	PerVideoDir      bool   `json:"per_video_dir,omitempty"`
+	TokenStore       string `json:"token_store,omitempty"`
+	TokenHelper      string `json:"token_helper,omitempty"`
+	// Backend is the download program, yt-dlp or youtube-dl; empty uses yt-dlp
+	// and falls back to youtube-dl. BackendPath and BackendArgs override its
+	// binary and add arguments to every download.
+	Backend     string   `json:"backend,omitempty"`
+	BackendPath string   `json:"backend_path,omitempty"`
+	BackendArgs []string `json:"backend_args,omitempty"`
+	// FormatProfiles are named format profiles for --format-profile
+	FormatProfiles map[string]*format.Profile `json:"format_profiles,omitempty"`
 }
 
-const configFile = "config.json"
-
-// Load loads the configuration from file
+// Load loads the active profile's configuration from file
 func Load() (*Config, error) {
-	configPath, err := getConfigPath()
+	configPath, err := constants.GetConfigPath()
 	if err != nil {
 		return nil, err
 	}
 
+	return LoadFrom(configPath)
+}
+
+// LoadFrom loads the configuration from the given file
+func LoadFrom(configPath string) (*Config, error) {
 	// Return default config if file doesn't exist
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return DefaultConfig(), nil
@@ -34,21 +52,27 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	// Start from the defaults so settings missing from the file keep their default values
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	return &cfg, nil
+	return cfg, nil
 }
 
-// Save saves the configuration to file
+// Save saves the configuration to the active profile's config file
 func (c *Config) Save() error {
-	configPath, err := getConfigPath()
+	configPath, err := constants.GetConfigPath()
 	if err != nil {
 		return err
 	}
 
+	return c.SaveTo(configPath)
+}
+
+// SaveTo saves the configuration to the given file
+func (c *Config) SaveTo(configPath string) error {
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
 		return err
@@ -73,17 +97,7 @@ func DefaultConfig() *Config {
 		DefaultQuality:   "best",
 		OutputDir:        "./downloads",
 		MaxConcurrent:    3,
+		OutputTemplate:   constants.DefaultOutputTemplate,
+		TokenStore:       constants.TokenStoreFile,
 	}
 }
-
-// getConfigPath returns the path to the config file
-func getConfigPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-
-	return filepath.Join(homeDir, ".yeetrap", configFile), nil
-}
-
-
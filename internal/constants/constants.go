@@ -3,6 +3,7 @@ package constants
 import (
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // Application constants
@@ -18,16 +19,66 @@ const (
 	ConfigDirName     = ".yeetrap"
 	CredentialsFile   = "credentials.json"
 	TokenFile         = "token.json"
+	EncryptedTokenFile = "token.enc.json"
 	ConfigFile        = "config.json"
 	DefaultOutputDir  = "./downloads"
+	ProfilesDirName   = "profiles"
+	ProfilesFile      = "profiles.json"
+)
+
+// Output directory state, kept in a hidden directory among the downloads
+const (
+	StateDirName   = ".yeetrap"
+	ArchiveFile    = "archive.json"
+	SyncStateFile  = "sync.json"
+	LogsDirName    = "logs"
+	UnfinishedFile = "unfinished.txt"
+	RemovedDirName = "_removed"
+)
+
+// Profile constants
+const (
+	DefaultProfileName = "default"
+	ProfileEnvVar      = "YEETRAP_PROFILE"
 )
 
 // YouTube API constants
 const (
 	YouTubeReadonlyScope = "https://www.googleapis.com/auth/youtube.readonly"
+	YouTubeForceSSLScope = "https://www.googleapis.com/auth/youtube.force-ssl"
+	YouTubeScope         = "https://www.googleapis.com/auth/youtube"
+	YouTubeAPIBaseURL    = "https://www.googleapis.com/youtube/v3/"
+	APIKeyEnvVar         = "YEETRAP_API_KEY"
 	MaxVideosPerPage     = 50
 	DefaultMaxVideos     = 50
 	DefaultConcurrency   = 3
+	// ShortsMaxDuration is the longest a Short can be; the API has no Shorts flag
+	ShortsMaxDuration = 3 * time.Minute
+)
+
+// Token store backends
+const (
+	TokenStoreFile        = "file"
+	TokenStoreEncrypted   = "encrypted"
+	TokenStoreHelper      = "helper"
+	TokenPassphraseEnvVar = "YEETRAP_TOKEN_PASSPHRASE"
+)
+
+// Download backends
+const (
+	BackendYtDlp     = "yt-dlp"
+	BackendYoutubeDL = "youtube-dl"
+)
+
+// OAuth2 flow constants
+const (
+	OAuthLoopbackHost    = "127.0.0.1"
+	OAuthCallbackPath    = "/callback"
+	OAuthCallbackTimeout = 5 * time.Minute
+	OAuthRevokeURL       = "https://oauth2.googleapis.com/revoke"
+	OAuthRevokeURLEnvVar = "YEETRAP_OAUTH_REVOKE_URL"
+	GooglePermissionsURL = "https://myaccount.google.com/permissions"
+	OAuthTokenInfoURL    = "https://oauth2.googleapis.com/tokeninfo"
 )
 
 // Video quality options
@@ -46,6 +97,8 @@ const (
 	DefaultQuality      = QualityBest
 	DefaultChannelID    = ""
 	DefaultMaxConcurrent = 3
+	// DefaultOutputTemplate names downloads; the ID keeps same-titled videos apart
+	DefaultOutputTemplate = "{{.Title}} [{{.ID}}]"
 )
 
 // Error messages
@@ -83,29 +136,66 @@ func GetConfigDir() (string, error) {
 	return configDir, nil
 }
 
-// GetCredentialsPath returns the full path to the credentials file
-func GetCredentialsPath() (string, error) {
+// activeProfile is the account profile whose files the path helpers resolve to
+var activeProfile = DefaultProfileName
+
+// SetActiveProfile selects the account profile used by the path helpers
+func SetActiveProfile(name string) {
+	activeProfile = name
+}
+
+// GetActiveProfile returns the name of the active account profile
+func GetActiveProfile() string {
+	return activeProfile
+}
+
+// GetProfilesDir returns the directory holding all account profiles
+func GetProfilesDir() (string, error) {
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, CredentialsFile), nil
+	return filepath.Join(configDir, ProfilesDirName), nil
 }
 
-// GetTokenPath returns the full path to the token file
+// GetProfileDir returns the directory of the active account profile
+func GetProfileDir() (string, error) {
+	profilesDir, err := GetProfilesDir()
+	if err != nil {
+		return "", err
+	}
+
+	profileDir := filepath.Join(profilesDir, activeProfile)
+	if err := os.MkdirAll(profileDir, 0700); err != nil {
+		return "", err
+	}
+
+	return profileDir, nil
+}
+
+// GetCredentialsPath returns the full path to the active profile's credentials file
+func GetCredentialsPath() (string, error) {
+	profileDir, err := GetProfileDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(profileDir, CredentialsFile), nil
+}
+
+// GetTokenPath returns the full path to the active profile's token file
 func GetTokenPath() (string, error) {
-	configDir, err := GetConfigDir()
+	profileDir, err := GetProfileDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, TokenFile), nil
+	return filepath.Join(profileDir, TokenFile), nil
 }
 
-// GetConfigPath returns the full path to the config file
+// GetConfigPath returns the full path to the active profile's config file
 func GetConfigPath() (string, error) {
-	configDir, err := GetConfigDir()
+	profileDir, err := GetProfileDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, ConfigFile), nil
+	return filepath.Join(profileDir, ConfigFile), nil
 }
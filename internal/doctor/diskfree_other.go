@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package doctor
+
+import "github.com/AlienFacepalm/YeeTrap/internal/errors"
+
+// diskFree is not supported on this platform
+func diskFree(path string) (uint64, error) {
+	return 0, errors.NewFileError("free space check not supported on this platform")
+}
@@ -0,0 +1,414 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/auth"
+	"github.com/AlienFacepalm/YeeTrap/internal/config"
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/downloader"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// Status is the outcome of a single check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Free space thresholds for the output directory
+const (
+	warnFreeBytes = 5 << 30
+	failFreeBytes = 500 << 20
+)
+
+// Result is the outcome of one diagnostic check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+// Report collects the results of all checks
+type Report struct {
+	Profile string   `json:"profile"`
+	Results []Result `json:"results"`
+}
+
+// Options controls what the checks look at
+type Options struct {
+	// OutputDir overrides the profile's configured output directory
+	OutputDir string
+}
+
+// Run performs all diagnostic checks
+func Run(opts Options) *Report {
+	logger.Info("Running diagnostics")
+
+	report := &Report{Profile: constants.GetActiveProfile()}
+
+	cfg, cfgResult := checkConfig()
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = cfg.OutputDir
+	}
+
+	report.add(checkCredentials())
+	report.add(checkToken())
+	report.add(cfgResult)
+	report.add(checkOutputDir(outputDir))
+	report.add(checkFreeSpace(outputDir))
+	report.add(checkBackend(cfg))
+	report.add(checkFFmpeg())
+	report.add(checkAPI())
+
+	return report
+}
+
+// HasFailures reports whether any check failed
+func (r *Report) HasFailures() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends a result
+func (r *Report) add(result Result) {
+	logger.Debug("Check %s: %s - %s", result.Name, result.Status, result.Message)
+	r.Results = append(r.Results, result)
+}
+
+// checkCredentials validates the profile's OAuth2 client credentials
+func checkCredentials() Result {
+	result := Result{Name: "Credentials"}
+
+	if err := auth.ValidateCredentials(true); err != nil {
+		result.Status = StatusFail
+		result.Message = errorMessage(err)
+		result.Details = errorDetails(err)
+		return result
+	}
+
+	if err := auth.ValidateCredentials(false); err != nil {
+		result.Status = StatusWarn
+		result.Message = "OAuth2 client credentials only support 'yeetrap auth --device'"
+		result.Details = errorDetails(err)
+		return result
+	}
+
+	result.Status = StatusPass
+	result.Message = "OAuth2 desktop client credentials are valid"
+	return result
+}
+
+// checkToken inspects the stored token's presence, expiry and scopes
+func checkToken() Result {
+	result := Result{Name: "Token"}
+
+	authenticator, err := auth.NewAuthenticator()
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = "cannot check token without valid credentials"
+		return result
+	}
+
+	status, err := authenticator.Status()
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = "no stored token"
+		result.Details = "Run 'yeetrap auth'"
+		return result
+	}
+
+	scopes := "scopes unknown"
+	if len(status.Scopes) > 0 {
+		scopes = "scopes: " + strings.Join(shortScopes(status.Scopes), ", ")
+	}
+
+	switch {
+	case status.Expired && !status.HasRefreshToken:
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("expired %v ago and has no refresh token", time.Since(status.Expiry).Round(time.Minute))
+		result.Details = "Run 'yeetrap auth'"
+	case !status.HasRefreshToken:
+		result.Status = StatusWarn
+		result.Message = fmt.Sprintf("no refresh token; %s", scopes)
+		result.Details = "The token cannot renew itself; run 'yeetrap auth' once it expires"
+	case len(status.Scopes) == 0:
+		result.Status = StatusWarn
+		result.Message = "present with refresh token; " + scopes
+		result.Details = "Scopes are recorded on the next API call"
+	default:
+		result.Status = StatusPass
+		result.Message = "present with refresh token; " + scopes
+		if status.Expired {
+			result.Details = "Access token expired; it will be refreshed automatically"
+		}
+	}
+
+	return result
+}
+
+// validateChannelRef checks the configured default channel, which may be a
+// channel ID, @handle or channel URL
+func validateChannelRef(value string) error {
+	if value == "" {
+		return nil
+	}
+	_, err := youtube.ParseChannelRef(value)
+	return err
+}
+
+// checkConfig loads and validates the profile's configuration
+func checkConfig() (*config.Config, Result) {
+	result := Result{Name: "Config"}
+
+	cfg, err := config.Load()
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = err.Error()
+		return config.DefaultConfig(), result
+	}
+
+	checks := []error{
+		validateChannelRef(cfg.DefaultChannelID),
+		validation.ValidateQuality(cfg.DefaultQuality),
+		format.ValidateConfigured(cfg.FormatProfiles),
+		validation.ValidateOutputDir(cfg.OutputDir),
+		validation.ValidateConcurrency(cfg.MaxConcurrent),
+	}
+	for _, err := range checks {
+		if err != nil {
+			result.Status = StatusFail
+			result.Message = errorMessage(err)
+			result.Details = errorDetails(err)
+			return cfg, result
+		}
+	}
+
+	path, _ := constants.GetConfigPath()
+	result.Status = StatusPass
+	result.Message = "configuration is valid"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		result.Message = "no config file; using defaults"
+	}
+	return cfg, result
+}
+
+// checkOutputDir verifies the output directory is usable and writable
+func checkOutputDir(outputDir string) Result {
+	result := Result{Name: "Output directory"}
+
+	if err := validation.ValidateOutputDir(outputDir); err != nil {
+		result.Status = StatusFail
+		result.Message = errorMessage(err)
+		return result
+	}
+
+	info, err := os.Stat(outputDir)
+	if os.IsNotExist(err) {
+		result.Status = StatusWarn
+		result.Message = fmt.Sprintf("%s does not exist yet; it will be created", outputDir)
+		return result
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = err.Error()
+		return result
+	}
+	if !info.IsDir() {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("%s is not a directory", outputDir)
+		return result
+	}
+
+	probe, err := os.CreateTemp(outputDir, ".yeetrap-doctor-*")
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("%s is not writable", outputDir)
+		result.Details = err.Error()
+		return result
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	result.Status = StatusPass
+	result.Message = fmt.Sprintf("%s is writable", outputDir)
+	return result
+}
+
+// checkFreeSpace reports the free space where downloads will be written
+func checkFreeSpace(outputDir string) Result {
+	result := Result{Name: "Free space"}
+
+	// Measure the nearest existing ancestor when the directory is not created yet
+	path, err := filepath.Abs(outputDir)
+	if err != nil {
+		path = outputDir
+	}
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+
+	free, err := diskFree(path)
+	if err != nil {
+		result.Status = StatusWarn
+		result.Message = "unable to determine free space"
+		result.Details = err.Error()
+		return result
+	}
+
+	result.Message = fmt.Sprintf("%s available", formatBytes(free))
+	switch {
+	case free < failFreeBytes:
+		result.Status = StatusFail
+		result.Details = "Free up disk space or choose another output directory"
+	case free < warnFreeBytes:
+		result.Status = StatusWarn
+		result.Details = "Large backups may not fit"
+	default:
+		result.Status = StatusPass
+	}
+	return result
+}
+
+// checkBackend verifies the download backend, yt-dlp unless configured
+// otherwise, is installed
+func checkBackend(cfg *config.Config) Result {
+	result := Result{Name: "yt-dlp"}
+
+	backend, err := downloader.SelectBackend(context.Background(), cfg.Backend, cfg.BackendPath, cfg.BackendArgs)
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = errorMessage(err)
+		result.Details = errorDetails(err)
+		return result
+	}
+	result.Name = backend.Name()
+
+	version, err := backend.Version()
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = errorMessage(err)
+		result.Details = errorDetails(err)
+		return result
+	}
+
+	result.Status = StatusPass
+	result.Message = "version " + version
+	if cfg.Backend == "" && backend.Name() == constants.BackendYoutubeDL {
+		result.Status = StatusWarn
+		result.Details = "yt-dlp is not installed; youtube-dl lacks codec, HDR and container settings of format profiles"
+	}
+	return result
+}
+
+// checkFFmpeg verifies ffmpeg is installed
+func checkFFmpeg() Result {
+	result := Result{Name: "ffmpeg"}
+
+	version, err := downloader.FFmpegVersion()
+	if err != nil {
+		result.Status = StatusWarn
+		result.Message = errorMessage(err)
+		result.Details = "yt-dlp needs ffmpeg to merge separate video and audio streams"
+		return result
+	}
+
+	result.Status = StatusPass
+	result.Message = "version " + version
+	return result
+}
+
+// checkAPI verifies the YouTube Data API endpoint is reachable
+func checkAPI() Result {
+	result := Result{Name: "YouTube API"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, constants.YouTubeAPIBaseURL, nil)
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = "unreachable"
+		result.Details = err.Error()
+		return result
+	}
+	resp.Body.Close()
+
+	// Any HTTP response (even 404 for the bare base URL) proves connectivity
+	result.Status = StatusPass
+	result.Message = fmt.Sprintf("reachable (%v)", time.Since(start).Round(time.Millisecond))
+	return result
+}
+
+// errorMessage returns the message of a YeeTrapError, or the error text
+func errorMessage(err error) string {
+	if ytErr, ok := err.(*errors.YeeTrapError); ok {
+		return ytErr.Message
+	}
+	return err.Error()
+}
+
+// errorDetails returns the details of a YeeTrapError, if any
+func errorDetails(err error) string {
+	if ytErr, ok := err.(*errors.YeeTrapError); ok {
+		return ytErr.Details
+	}
+	return ""
+}
+
+// shortScopes strips the common Google scope prefix for display
+func shortScopes(scopes []string) []string {
+	short := make([]string, len(scopes))
+	for i, scope := range scopes {
+		short[i] = strings.TrimPrefix(scope, "https://www.googleapis.com/auth/")
+	}
+	return short
+}
+
+// formatBytes renders a byte count in binary units
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
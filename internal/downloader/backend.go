@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// Backend fetches videos for the Downloader. The yt-dlp backend runs an
+// external program; FakeBackend writes deterministic files instead, so the
+// download, retry and archive logic can run without a network.
+type Backend interface {
+	// Name identifies the backend in messages, e.g. "yt-dlp"
+	Name() string
+	// Version returns the backend's version
+	Version() (string, error)
+	// Probe checks that the backend is usable before a run starts
+	Probe(ctx context.Context) error
+	// Download fetches one video in one format, reporting byte progress to
+	// onProgress. It stops when ctx is cancelled.
+	Download(ctx context.Context, req Request, onProgress ProgressFunc) error
+}
+
+// Request describes a single download
+type Request struct {
+	Video youtube.Video
+	// Format selects the formats to fetch and how to store them
+	Format *format.Profile
+	// OutputPath is where the media goes, without extension
+	OutputPath string
+	// MetadataPath, when set, is where the description, info JSON and
+	// thumbnail go, without extension
+	MetadataPath string
+	// Log receives the backend's output
+	Log io.Writer
+}
+
+// URL returns the watch page of the requested video
+func (r Request) URL() string {
+	return fmt.Sprintf("https://www.youtube.com/watch?v=%s", r.Video.ID)
+}
+
+// Progress is the byte progress of a download
+type Progress struct {
+	Downloaded int64
+	Total      int64
+	Speed      float64
+	ETA        time.Duration
+}
+
+// ProgressFunc receives progress updates of a download
+type ProgressFunc func(Progress)
+
+// SelectBackend returns the download backend named in the config: yt-dlp,
+// youtube-dl, or for an empty name yt-dlp with youtube-dl as fallback when
+// only that is installed. path overrides the binary and args are added to
+// every download.
+func SelectBackend(ctx context.Context, name, path string, args []string) (Backend, error) {
+	switch name {
+	case constants.BackendYtDlp:
+		return NewYtDlp(path, args), nil
+	case constants.BackendYoutubeDL:
+		return NewYoutubeDL(path, args), nil
+	case "":
+	default:
+		return nil, errors.NewConfigError(fmt.Sprintf("unknown download backend: %s", name)).
+			WithDetails(fmt.Sprintf("Supported backends: %s, %s", constants.BackendYtDlp, constants.BackendYoutubeDL))
+	}
+
+	ytdlp := NewYtDlp(path, args)
+	if path != "" || ytdlp.Probe(ctx) == nil {
+		return ytdlp, nil
+	}
+
+	youtubeDL := NewYoutubeDL("", args)
+	if youtubeDL.Probe(ctx) != nil {
+		// Let the run fail with yt-dlp's install hint
+		return ytdlp, nil
+	}
+	logger.Warn("yt-dlp is not installed; falling back to youtube-dl, which lacks some format options")
+	return youtubeDL, nil
+}
@@ -0,0 +1,77 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// CaptionFormat is the format caption tracks are saved in
+const CaptionFormat = "srt"
+
+// CaptionSource fetches the caption tracks of a video
+type CaptionSource interface {
+	ListCaptions(videoID string) ([]youtube.Caption, error)
+	DownloadCaption(captionID, format string) ([]byte, error)
+}
+
+// DownloadCaptions saves every caption track of the given videos next to the
+// video files, as <title>.<language>[.<track name>].srt
+func (d *Downloader) DownloadCaptions(videos []youtube.Video, source CaptionSource) error {
+	logger.Info("Downloading captions for %d videos", len(videos))
+
+	var failed int
+	for _, video := range videos {
+		if err := d.downloadVideoCaptions(video, source); err != nil {
+			logger.Error("Failed to download captions for %s: %v", video.Title, err)
+			fmt.Printf("  - captions for %s: %v\n", video.Title, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.NewAPIError(fmt.Sprintf("captions failed for %d video(s)", failed))
+	}
+	return nil
+}
+
+// downloadVideoCaptions saves the caption tracks of a single video
+func (d *Downloader) downloadVideoCaptions(video youtube.Video, source CaptionSource) error {
+	captions, err := source.ListCaptions(video.ID)
+	if err != nil {
+		return errors.WrapAPI(err, "unable to list captions")
+	}
+
+	base, err := d.basePath(video)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return errors.WrapFile(err, "failed to create output directory")
+	}
+	for _, caption := range captions {
+		data, err := source.DownloadCaption(caption.ID, CaptionFormat)
+		if err != nil {
+			return errors.WrapAPI(err, fmt.Sprintf("unable to download %s captions", caption.Language))
+		}
+
+		suffix := caption.Language
+		if caption.Name != "" {
+			suffix += "." + validation.SanitizeFilename(strings.ReplaceAll(caption.Name, ".", "_"))
+		}
+
+		path := fmt.Sprintf("%s.%s.%s", base, suffix, CaptionFormat)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return errors.WrapFile(err, "unable to write caption file")
+		}
+		logger.Debug("Saved captions: %s", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+// stderrTailLines is how much of yt-dlp's error output a failure keeps
+const stderrTailLines = 20
+
+// failureClass maps yt-dlp error messages to a failure kind
+type failureClass struct {
+	kind    errors.ErrorType
+	pattern *regexp.Regexp
+	details string
+}
+
+// failureClasses are checked in order, so e.g. "Private video. Sign in if
+// you've been granted access" counts as private rather than auth-required
+var failureClasses = []failureClass{
+	{
+		kind:    errors.ErrorTypeDiskFull,
+		pattern: regexp.MustCompile(`(?i)no space left on device|disk quota exceeded|not enough space on the disk`),
+		details: "Free up space in the output directory and run again",
+	},
+	{
+		kind:    errors.ErrorTypePrivate,
+		pattern: regexp.MustCompile(`(?i)private video|this video is private`),
+		details: "Private videos need cookies of an account with access, e.g. backend_args [\"--cookies-from-browser\", \"firefox\"]",
+	},
+	{
+		kind:    errors.ErrorTypeAuthRequired,
+		pattern: regexp.MustCompile(`(?i)sign in to confirm your age|age[- ]restricted|members[- ]only|join this channel|available to this channel's members|requires payment|use --cookies|login required|sign in to confirm you.re not a bot`),
+		details: "The video needs a signed-in account; pass cookies through backend_args, e.g. [\"--cookies-from-browser\", \"firefox\"]",
+	},
+	{
+		kind:    errors.ErrorTypeGeoBlocked,
+		pattern: regexp.MustCompile(`(?i)not (made this video )?available in your country|geo[- ]?restrict|blocked it in your country`),
+		details: "The video is blocked in your country; a --proxy in backend_args may help",
+	},
+	{
+		kind:    errors.ErrorTypeRateLimited,
+		pattern: regexp.MustCompile(`(?i)http error 429|too many requests|rate[- ]limit`),
+		details: "YouTube is limiting requests; wait a while or lower --concurrent before running again",
+	},
+	{
+		kind:    errors.ErrorTypeUnavailable,
+		pattern: regexp.MustCompile(`(?i)video unavailable|no longer available|has been removed|has been terminated|does not exist|http error 404|this live event will begin|premieres in`),
+		details: "The video was removed or cannot be downloaded (yet)",
+	},
+	{
+		kind:    errors.ErrorTypeTransient,
+		pattern: regexp.MustCompile(`(?i)timed? ?out|connection (reset|refused|aborted)|remote end closed|temporary failure|name resolution|incompleteread|http error 5\d\d|unable to download (webpage|video data)|got error|giving up after`),
+	},
+}
+
+// errorLinePrefix starts the lines yt-dlp reports the failure on
+const errorLinePrefix = "ERROR:"
+
+// classifyFailure turns a failed yt-dlp run into an error of the matching
+// kind, with the class and the tail of stderr in its context. ERROR lines are
+// matched first, the whole tail only if they match nothing.
+func classifyFailure(backend string, req Request, cause error, stderrTail string) *errors.YeeTrapError {
+	errorLines := errorLines(stderrTail)
+
+	class := failureClass{kind: errors.ErrorTypeExternal}
+	for _, text := range []string{strings.Join(errorLines, "\n"), stderrTail} {
+		if matched, ok := matchFailure(text); ok {
+			class = matched
+			break
+		}
+	}
+
+	what := req.Video.ID
+	if !req.Format.Primary() {
+		what += " in format profile " + req.Format.Name
+	}
+	message := fmt.Sprintf("%s failed for video %s", backend, what)
+	if len(errorLines) > 0 {
+		message += ": " + errorReason(errorLines[len(errorLines)-1])
+	}
+
+	err := errors.Wrap(cause, class.kind, message).
+		WithContext(errors.ContextClass, class.kind).
+		WithContext(errors.ContextStderrTail, stderrTail)
+	if class.details != "" {
+		err.WithDetails(class.details)
+	}
+	return err
+}
+
+// matchFailure finds the first failure class matching text
+func matchFailure(text string) (failureClass, bool) {
+	if text == "" {
+		return failureClass{}, false
+	}
+	for _, class := range failureClasses {
+		if class.pattern.MatchString(text) {
+			return class, true
+		}
+	}
+	return failureClass{}, false
+}
+
+// errorLines returns the ERROR lines of yt-dlp's output
+func errorLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), errorLinePrefix) {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return lines
+}
+
+// errorReasonPrefix matches the "ERROR: [youtube] <id>: " start of a line
+var errorReasonPrefix = regexp.MustCompile(`^ERROR:\s*(\[[^\]]+\]\s*([\w-]+:\s)?)?`)
+
+// errorReason returns the message of an ERROR line
+func errorReason(line string) string {
+	return strings.TrimSpace(errorReasonPrefix.ReplaceAllString(line, ""))
+}
+
+// tailBuffer keeps the last lines written to it
+type tailBuffer struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+// newTailBuffer returns a buffer keeping the last max lines
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+// Write implements io.Writer
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.lines = append(t.lines, strings.TrimRight(string(data[:i]), "\r"))
+		if len(t.lines) > t.max {
+			t.lines = t.lines[len(t.lines)-t.max:]
+		}
+		data = data[i+1:]
+	}
+	t.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// String returns the kept lines, counting an unterminated last one
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := t.lines
+	if len(t.partial) > 0 {
+		lines = append(append([]string(nil), lines...), string(t.partial))
+		if len(lines) > t.max {
+			lines = lines[len(lines)-t.max:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,145 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
+	"github.com/AlienFacepalm/YeeTrap/internal/retry"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		stderr    string
+		wantType  errors.ErrorType
+		wantRetry bool
+		wantMsg   string
+	}{
+		{
+			name:     "rate limited",
+			stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Unable to download webpage: HTTP Error 429: Too Many Requests (caused by <HTTPError 429: Too Many Requests>)",
+			wantType: errors.ErrorTypeRateLimited,
+			wantMsg:  "Unable to download webpage: HTTP Error 429",
+		},
+		{
+			name:      "forbidden video data",
+			stderr:    "[download] Destination: Title [dQw4w9WgXcQ].f137.mp4\nERROR: unable to download video data: HTTP Error 403: Forbidden",
+			wantType:  errors.ErrorTypeTransient,
+			wantRetry: true,
+			wantMsg:   "unable to download video data: HTTP Error 403: Forbidden",
+		},
+		{
+			name:     "private video",
+			stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Private video. Sign in if you've been granted access to this video",
+			wantType: errors.ErrorTypePrivate,
+			wantMsg:  "Private video. Sign in",
+		},
+		{
+			name:     "video unavailable",
+			stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable. This video has been removed by the uploader",
+			wantType: errors.ErrorTypeUnavailable,
+			wantMsg:  "Video unavailable",
+		},
+		{
+			name:     "age gate",
+			stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Sign in to confirm your age. This video may be inappropriate for some users. Use --cookies-from-browser or --cookies for the authentication.",
+			wantType: errors.ErrorTypeAuthRequired,
+			wantMsg:  "Sign in to confirm your age",
+		},
+		{
+			name:     "members only",
+			stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Join this channel to get access to members-only content like this video, and other exclusive perks.",
+			wantType: errors.ErrorTypeAuthRequired,
+		},
+		{
+			name:     "geo blocked",
+			stderr:   "ERROR: [youtube] dQw4w9WgXcQ: The uploader has not made this video available in your country",
+			wantType: errors.ErrorTypeGeoBlocked,
+		},
+		{
+			name:     "disk full without ERROR line",
+			stderr:   "Traceback (most recent call last):\nOSError: [Errno 28] No space left on device",
+			wantType: errors.ErrorTypeDiskFull,
+		},
+		{
+			name:      "network timeout",
+			stderr:    "ERROR: [youtube] dQw4w9WgXcQ: Unable to download API page: <urlopen error timed out> (caused by TransportError('<urlopen error timed out>'))",
+			wantType:  errors.ErrorTypeTransient,
+			wantRetry: true,
+		},
+		{
+			name:      "connection reset",
+			stderr:    "ERROR: [download] Got error: [Errno 104] Connection reset by peer. Giving up after 10 retries",
+			wantType:  errors.ErrorTypeTransient,
+			wantRetry: true,
+		},
+		{
+			name:     "unknown output",
+			stderr:   "ERROR: Postprocessing: ffprobe and ffmpeg not found. Please install or provide the path using --ffmpeg-location",
+			wantType: errors.ErrorTypeExternal,
+			wantMsg:  "Postprocessing: ffprobe and ffmpeg not found",
+		},
+		{
+			name:     "no output",
+			wantType: errors.ErrorTypeExternal,
+		},
+	}
+
+	profile, _ := format.Builtin("best")
+	req := Request{Video: youtube.Video{ID: "dQw4w9WgXcQ"}, Format: profile}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyFailure("yt-dlp", req, fmt.Errorf("exit status 1"), tt.stderr)
+
+			if err.Type != tt.wantType {
+				t.Errorf("type = %q, want %q", err.Type, tt.wantType)
+			}
+			if class := errors.Class(err); class != tt.wantType {
+				t.Errorf("Class() = %q, want %q", class, tt.wantType)
+			}
+			if tail := err.Context[errors.ContextStderrTail]; tail != tt.stderr {
+				t.Errorf("stderr tail = %q, want %q", tail, tt.stderr)
+			}
+			if !strings.HasPrefix(err.Message, "yt-dlp failed for video dQw4w9WgXcQ") || !strings.Contains(err.Message, tt.wantMsg) {
+				t.Errorf("message = %q, want the video and %q", err.Message, tt.wantMsg)
+			}
+			if got := retry.IsRetryableError(err); got != tt.wantRetry {
+				t.Errorf("IsRetryableError() = %v, want %v", got, tt.wantRetry)
+			}
+
+			// The downloader wraps failures before reporting them
+			wrapped := errors.WrapExternal(err, "failed to download video")
+			if class := errors.Class(wrapped); class != tt.wantType {
+				t.Errorf("Class() of wrapped error = %q, want %q", class, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestClassifyFailureProfile(t *testing.T) {
+	profile, err := format.Lookup("podcast", map[string]*format.Profile{"podcast": {AudioOnly: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := Request{Video: youtube.Video{ID: "dQw4w9WgXcQ"}, Format: profile}
+
+	got := classifyFailure("yt-dlp", req, fmt.Errorf("exit status 1"), "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable")
+	if want := "yt-dlp failed for video dQw4w9WgXcQ in format profile podcast: Video unavailable"; got.Message != want {
+		t.Errorf("message = %q, want %q", got.Message, want)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tail := newTailBuffer(2)
+	fmt.Fprint(tail, "one\ntwo\r\nthr")
+	fmt.Fprint(tail, "ee\nfour")
+
+	if got, want := tail.String(), "three\nfour"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
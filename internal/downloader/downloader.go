@@ -1,14 +1,21 @@
 package downloader
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/AlienFacepalm/YeeTrap/internal/archive"
 	"github.com/AlienFacepalm/YeeTrap/internal/constants"
 	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
+	"github.com/AlienFacepalm/YeeTrap/internal/interrupt"
 	"github.com/AlienFacepalm/YeeTrap/internal/logger"
 	"github.com/AlienFacepalm/YeeTrap/internal/progress"
 	"github.com/AlienFacepalm/YeeTrap/internal/retry"
@@ -19,9 +26,24 @@ import (
 // Downloader handles video downloads
 type Downloader struct {
 	outputDir  string
-	quality    string
 	concurrent int
+	numbered   bool
+	force      bool
+	template   *OutputTemplate
 	progress   *progress.ProgressTracker
+	// formats are the format profiles each video is downloaded in
+	formats []*format.Profile
+	backend Backend
+	// perVideoDir puts each video's files into a directory of their own
+	perVideoDir bool
+	// paths holds the output paths planned for videos, relative to outputDir
+	paths map[string]string
+	// progressMode selects the progress display; events receives the
+	// progress.ModeJSON event stream
+	progressMode string
+	events       io.Writer
+	// retryConfig returns the retry policy of each download
+	retryConfig func() *retry.RetryConfig
 }
 
 // NewDownloader creates a new downloader
@@ -39,68 +61,193 @@ func NewDownloader(outputDir, quality string, concurrent int) (*Downloader, erro
 		return nil, err
 	}
 	
+	tmpl, err := ParseOutputTemplate(constants.DefaultOutputTemplate)
+	if err != nil {
+		return nil, err
+	}
+	
+	profile, _ := format.Builtin(quality)
+	
 	logger.Info("Creating downloader with output: %s, quality: %s, concurrent: %d", outputDir, quality, concurrent)
 	
 	return &Downloader{
-		outputDir:  outputDir,
-		quality:    quality,
-		concurrent: concurrent,
+		outputDir:    outputDir,
+		concurrent:   concurrent,
+		formats:      []*format.Profile{profile},
+		backend:      NewYtDlp("", nil),
+		template:     tmpl,
+		paths:        make(map[string]string),
+		progressMode: progress.ModeAuto,
+		events:       os.Stderr,
+		retryConfig:  retry.DownloadRetryConfig,
 	}, nil
 }
 
-// DownloadVideos downloads multiple videos with concurrency control
-func (d *Downloader) DownloadVideos(videos []youtube.Video) error {
+// SetNumbered prefixes file names with the playlist position of each video
+// (e.g. "007 - Title"), so files sort in playlist order
+func (d *Downloader) SetNumbered(numbered bool) {
+	d.numbered = numbered
+}
+
+// SetOutputTemplate names downloaded files with tmpl; with perVideoDir each
+// video's files go into a directory named like them
+func (d *Downloader) SetOutputTemplate(tmpl *OutputTemplate, perVideoDir bool) {
+	d.template = tmpl
+	d.perVideoDir = perVideoDir
+}
+
+// SetFormats downloads each video in every given format profile instead of
+// the quality passed to NewDownloader. At most one of them may be primary.
+func (d *Downloader) SetFormats(profiles []*format.Profile) {
+	d.formats = profiles
+}
+
+// SetBackend replaces the default yt-dlp backend
+func (d *Downloader) SetBackend(backend Backend) {
+	d.backend = backend
+}
+
+// SetForce downloads videos again even if the archive lists them
+func (d *Downloader) SetForce(force bool) {
+	d.force = force
+}
+
+// SetProgress selects how progress is shown (progress.ModeAuto, ModePlain or
+// ModeJSON) and where ModeJSON writes its events
+func (d *Downloader) SetProgress(mode string, events io.Writer) {
+	d.progressMode = mode
+	d.events = events
+}
+
+// DownloadVideos downloads multiple videos with concurrency control, in each
+// format profile. Copies recorded in the output directory's archive are
+// skipped unless forced.
+//
+// Cancelling ctx stops starting new downloads while those in progress finish;
+// cancelling interrupt.Abort(ctx) stops those too and removes their partial
+// files. Either way the videos left undone are listed in the unfinished file
+// for a later --resume.
+func (d *Downloader) DownloadVideos(ctx context.Context, videos []youtube.Video) error {
 	logger.Info("Starting download of %d videos", len(videos))
 	
-	// Check if yt-dlp is available
-	if err := d.checkYtDlp(); err != nil {
+	// Check if the backend is available and can handle the formats
+	if err := d.backend.Probe(ctx); err != nil {
 		return err
 	}
+	if backend, ok := d.backend.(*YtDlp); ok && backend.legacy {
+		for _, profile := range d.formats {
+			if err := legacyCompatible(profile); err != nil {
+				return err
+			}
+		}
+	}
 
 	// Create output directory
 	if err := os.MkdirAll(d.outputDir, 0755); err != nil {
 		return errors.WrapFile(err, "failed to create output directory")
 	}
 
-	// Initialize progress tracker
+	downloadArchive, err := archive.Open(d.outputDir)
+	if err != nil {
+		return err
+	}
+
+	videos, pending := d.skipArchived(videos, downloadArchive)
+	if len(videos) == 0 {
+		fmt.Println("✅ All videos are already backed up")
+		if d.progressMode == progress.ModeJSON {
+			// Consumers still get a complete, empty run
+			progress.NewJSONEmitter(d.events, os.Stdout).Finish(&progress.Progress{StartTime: time.Now()})
+		}
+		return nil
+	}
+
+	if err := d.planPaths(videos); err != nil {
+		return err
+	}
+
+	// Initialize progress tracker; log output goes through the display so it
+	// doesn't tear the dashboard
 	d.progress = progress.NewProgressTracker(len(videos))
-	d.progress.AddCallback(progress.DefaultProgressCallback)
-	defer d.progress.Stop()
+	display := progress.NewDisplay(os.Stdout, d.progressMode, d.events)
+	d.progress.AddCallback(display.Update)
+	for _, video := range videos {
+		d.progress.QueueItem(video.ID, video.Title)
+	}
+	previousOutput := logger.SetOutput(display)
 
 	var wg sync.WaitGroup
+	var mu sync.Mutex
+	completed := make(map[string]bool, len(videos))
 	semaphore := make(chan struct{}, d.concurrent)
 	downloadErrors := make(chan error, len(videos))
 
-	for i, video := range videos {
+queue:
+	for _, video := range videos {
+		// Once interrupted, no further downloads start. select picks at
+		// random when both cases are ready, so check before and after.
+		if ctx.Err() != nil {
+			break queue
+		}
+		select {
+		case semaphore <- struct{}{}: // Acquire
+		case <-ctx.Done():
+			break queue
+		}
+		if ctx.Err() != nil {
+			<-semaphore
+			break queue
+		}
+
 		wg.Add(1)
-		go func(idx int, v youtube.Video) {
+		go func(v youtube.Video) {
 			defer wg.Done()
-
-			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
 
 			// Update progress
-			d.progress.UpdateProgress(idx, 0, fmt.Sprintf("Downloading: %s", v.Title))
+			d.progress.StartItem(v.ID, v.Title)
 			
-			// Download with retry logic
-			err := retry.RetryDownloadOperation(func() error {
-				return d.downloadVideo(v)
-			})
+			err := d.downloadFormats(ctx, v, pending[v.ID], downloadArchive)
 			
-			if err != nil {
-				logger.Error("Failed to download %s: %v", v.Title, err)
-				d.progress.IncrementFailed(v.Title)
+			switch {
+			case err == nil:
+				logger.Debug("Successfully downloaded: %s", v.Title)
+				mu.Lock()
+				completed[v.ID] = true
+				mu.Unlock()
+			case interrupt.Abort(ctx).Err() != nil || errors.Is(err, context.Canceled):
+				// Stopped by the interrupt rather than failed; downloads
+				// allowed to finish still report their real failures
+				logger.Debug("Download of %s interrupted: %v", v.Title, err)
+				err = errInterrupted
+			default:
+				logger.Debug("Failed to download %s: %v", v.Title, err)
 				downloadErrors <- errors.WrapExternal(err, fmt.Sprintf("failed to download %s", v.Title))
-			} else {
-				logger.Info("Successfully downloaded: %s", v.Title)
-				d.progress.IncrementCompleted(v.Title)
 			}
-		}(i, video)
+			d.progress.FinishItem(v.ID, err)
+		}(video)
 	}
 
 	wg.Wait()
 	close(downloadErrors)
 
+	d.progress.Stop()
+	display.Finish(d.progress.GetProgress())
+	logger.SetOutput(previousOutput)
+
+	var unfinished []youtube.Video
+	if ctx.Err() != nil {
+		for _, video := range videos {
+			if !completed[video.ID] {
+				unfinished = append(unfinished, video)
+			}
+		}
+	}
+	unfinishedPath := UnfinishedPath(d.outputDir)
+	if err := updateUnfinished(unfinishedPath, completed, unfinished); err != nil {
+		logger.Warn("Unable to update the list of unfinished videos: %v", err)
+	}
+
 	// Collect all errors
 	var downloadErrorsList []error
 	for err := range downloadErrors {
@@ -113,6 +260,13 @@ func (d *Downloader) DownloadVideos(videos []youtube.Video) error {
 		for _, err := range downloadErrorsList {
 			fmt.Printf("  - %v\n", err)
 		}
+	}
+
+	if len(unfinished) > 0 {
+		return errors.NewExternalError(fmt.Sprintf("download interrupted with %d video(s) unfinished; they are listed in %s, run again with --resume to continue",
+			len(unfinished), unfinishedPath))
+	}
+	if len(downloadErrorsList) > 0 {
 		return errors.NewExternalError(fmt.Sprintf("%d download(s) failed", len(downloadErrorsList)))
 	}
 
@@ -120,66 +274,225 @@ func (d *Downloader) DownloadVideos(videos []youtube.Video) error {
 	return nil
 }
 
-// downloadVideo downloads a single video using yt-dlp
-func (d *Downloader) downloadVideo(video youtube.Video) error {
-	logger.Debug("Downloading video: %s (%s)", video.Title, video.ID)
-	
-	url := fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.ID)
+// skipArchived returns the format profiles each video still needs, dropping
+// videos archived in all of them unless forced
+func (d *Downloader) skipArchived(videos []youtube.Video, downloadArchive *archive.Archive) ([]youtube.Video, map[string][]*format.Profile) {
+	queued := make([]youtube.Video, 0, len(videos))
+	pending := make(map[string][]*format.Profile, len(videos))
+	for _, video := range videos {
+		for _, profile := range d.formats {
+			if d.force || !downloadArchive.HasProfile(video.ID, profile.Key()) {
+				pending[video.ID] = append(pending[video.ID], profile)
+			}
+		}
+		if len(pending[video.ID]) == 0 {
+			logger.Debug("Skipping archived video %s (%s)", video.Title, video.ID)
+			continue
+		}
+		queued = append(queued, video)
+	}
+
+	if skipped := len(videos) - len(queued); skipped > 0 {
+		fmt.Printf("⏭️  Skipping %d already backed-up video(s) (use --force to download again)\n", skipped)
+		logger.Info("Skipped %d archived videos", skipped)
+	}
+	return queued, pending
+}
+
+// downloadFormats downloads a video in each of the given format profiles in
+// turn, archiving every finished copy right away. The first copy also writes
+// the metadata files unless they belong to an archived main copy.
+func (d *Downloader) downloadFormats(ctx context.Context, video youtube.Video, profiles []*format.Profile, downloadArchive *archive.Archive) error {
+	abortCtx := interrupt.Abort(ctx)
+
+	for i, profile := range profiles {
+		metadata := i == 0 && (profile.Primary() || !downloadArchive.Has(video.ID))
+
+		// Download with retry logic; no new attempt starts once interrupted
+		config := d.retryConfig()
+		config.OnRetry = func(attempt int, err error, delay time.Duration) {
+			logger.Warn("Attempt %d for %s failed: %v. Retrying in %v...", attempt, video.Title, err, delay.Round(time.Second))
+			d.progress.RetryItem(video.ID, attempt, err)
+		}
+		attempted := false
+		err := retry.RetryWithContext(ctx, func(context.Context) error {
+			attempted = true
+			return d.downloadVideo(abortCtx, video, profile, metadata)
+		}, config)
+		if err != nil {
+			// Only clean up after a download this run started; files of a
+			// video that never ran may be complete copies not yet archived
+			if ctx.Err() != nil && attempted {
+				d.removePartial(video, profile, downloadArchive)
+			}
+			return err
+		}
+
+		if err := d.record(downloadArchive, video, profile); err != nil {
+			logger.Warn("Unable to record %s in the download archive: %v", video.ID, err)
+		}
+	}
+	return nil
+}
+
+// downloadVideo downloads a single video in a format profile through the
+// backend, stopping it when ctx is cancelled. With metadata the description,
+// info JSON and thumbnail are written next to the main copy.
+func (d *Downloader) downloadVideo(ctx context.Context, video youtube.Video, profile *format.Profile, metadata bool) error {
+	logger.Debug("Downloading video: %s (%s) as %s", video.Title, video.ID, profile)
 	
-	// Sanitize filename
-	filename := validation.SanitizeFilename(video.Title)
-	outputPath := filepath.Join(d.outputDir, filename+".%(ext)s")
-
-	args := []string{
-		"-f", d.getFormatString(),
-		"-o", outputPath,
-		"--no-playlist",
-		"--write-description",
-		"--write-info-json",
-		"--write-thumbnail",
-		"--no-warnings",
-		url,
-	}
-
-	cmd := exec.Command("yt-dlp", args...)
-	// Capture output for logging instead of printing to stdout
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	err := cmd.Run()
+	base, err := d.basePath(video)
 	if err != nil {
-		return errors.WrapExternal(err, fmt.Sprintf("yt-dlp failed for video %s", video.ID))
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return errors.WrapFile(err, "failed to create video directory")
+	}
+
+	req := Request{Video: video, Format: profile, OutputPath: base + profile.Suffix()}
+	if metadata {
+		req.MetadataPath = base
+	}
+
+	// Backend output goes to a per-video log so concurrent downloads don't
+	// interleave on the terminal; progress feeds the tracker instead
+	logFile, err := d.openLog(video.ID, profile)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	req.Log = logFile
+
+	err = d.backend.Download(ctx, req, func(p Progress) {
+		if d.progress != nil {
+			d.progress.UpdateItem(video.ID, p.Downloaded, p.Total, p.Speed, p.ETA)
+		}
+	})
+	if err != nil {
+		// Backends may return classified errors, whose kind decides retries
+		ytErr, ok := err.(*errors.YeeTrapError)
+		if !ok {
+			what := video.ID
+			if !profile.Primary() {
+				what += " in format profile " + profile.Name
+			}
+			ytErr = errors.WrapExternal(err, fmt.Sprintf("%s failed for video %s", d.backend.Name(), what))
+		}
+		details := fmt.Sprintf("See the %s log: %s", d.backend.Name(), logFile.Name())
+		if ytErr.Details != "" {
+			details = ytErr.Details + ". " + details
+		}
+		return ytErr.WithContext("log", logFile.Name()).WithDetails(details)
 	}
 	
 	return nil
 }
 
-// checkYtDlp checks if yt-dlp is installed
-func (d *Downloader) checkYtDlp() error {
-	logger.Debug("Checking if yt-dlp is available")
-	
-	cmd := exec.Command("yt-dlp", "--version")
-	if err := cmd.Run(); err != nil {
-		return errors.NewExternalError("yt-dlp is not installed or not in PATH").
-			WithDetails("Please install it from https://github.com/yt-dlp/yt-dlp")
+// logPath returns the download log file of a video
+func (d *Downloader) logPath(videoID string) string {
+	return filepath.Join(d.outputDir, constants.StateDirName, constants.LogsDirName, videoID+".log")
+}
+
+// openLog opens the per-video log for appending, starting a new section for
+// this attempt
+func (d *Downloader) openLog(videoID string, profile *format.Profile) (*os.File, error) {
+	path := d.logPath(videoID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.WrapFile(err, "unable to create log directory")
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to open download log")
+	}
+
+	fmt.Fprintf(file, "=== %s %s, format %s\n", time.Now().Format(time.RFC3339), d.backend.Name(), profile)
+	return file, nil
+}
+
+// record adds a video's copy in a format profile to the archive
+func (d *Downloader) record(downloadArchive *archive.Archive, video youtube.Video, profile *format.Profile) error {
+	base, err := d.basePath(video)
+	if err != nil {
+		return err
+	}
+	_, err = downloadArchive.RecordFiles(video.ID, video.Title, profile.Key(), profile.String(), base+profile.Suffix())
+	return err
+}
+
+// videoPath renders the output path of a video relative to the output
+// directory, without extension
+func (d *Downloader) videoPath(video youtube.Video) (string, error) {
+	rel, err := d.template.Render(video)
+	if err != nil {
+		return "", err
+	}
+	if d.numbered && video.PlaylistIndex > 0 {
+		dir, name := filepath.Split(rel)
+		rel = filepath.Join(dir, fmt.Sprintf("%03d - %s", video.PlaylistIndex, name))
+	}
+	return rel, nil
+}
+
+// planPaths renders the output paths of a run. Videos whose path is taken by
+// another video, e.g. same-titled videos with a template lacking the ID, get
+// their ID appended instead of overwriting the other's files.
+func (d *Downloader) planPaths(videos []youtube.Video) error {
+	// Compare case-insensitively, as on Windows and macOS file systems
+	taken := make(map[string]string, len(d.paths)+len(videos))
+	for id, rel := range d.paths {
+		taken[strings.ToLower(rel)] = id
+	}
+
+	for _, video := range videos {
+		rel, err := d.videoPath(video)
+		if err != nil {
+			return err
+		}
+		if owner, ok := taken[strings.ToLower(rel)]; ok && owner != video.ID {
+			rel = fmt.Sprintf("%s [%s]", rel, video.ID)
+			logger.Warn("%s would overwrite the files of %s; saving it as %s", video.ID, owner, rel)
+		}
+		taken[strings.ToLower(rel)] = video.ID
+		d.paths[video.ID] = rel
 	}
-	
-	logger.Debug("yt-dlp is available")
 	return nil
 }
 
-// getFormatString returns the yt-dlp format string based on quality setting
-func (d *Downloader) getFormatString() string {
-	switch d.quality {
-	case constants.Quality1080p:
-		return "bestvideo[height<=1080]+bestaudio/best[height<=1080]"
-	case constants.Quality720p:
-		return "bestvideo[height<=720]+bestaudio/best[height<=720]"
-	case constants.Quality480p:
-		return "bestvideo[height<=480]+bestaudio/best[height<=480]"
-	default:
-		return "bestvideo+bestaudio/best"
+// basePath returns the output path of a video without extension
+func (d *Downloader) basePath(video youtube.Video) (string, error) {
+	rel, ok := d.paths[video.ID]
+	if !ok {
+		var err error
+		if rel, err = d.videoPath(video); err != nil {
+			return "", err
+		}
+	}
+	if d.perVideoDir {
+		rel = filepath.Join(rel, filepath.Base(rel))
+	}
+	return filepath.Join(d.outputDir, rel), nil
+}
+
+// FFmpegVersion returns the installed ffmpeg version, which yt-dlp needs to
+// merge separate video and audio streams
+func FFmpegVersion() (string, error) {
+	logger.Debug("Checking if ffmpeg is available")
+	
+	out, err := exec.Command("ffmpeg", "-version").Output()
+	if err != nil {
+		return "", errors.NewExternalError("ffmpeg is not installed or not in PATH").
+			WithDetails("Please install it from https://ffmpeg.org/download.html")
 	}
+	
+	// First line looks like: ffmpeg version 6.1.1 Copyright (c) ...
+	version := strings.SplitN(string(out), "\n", 2)[0]
+	if fields := strings.Fields(version); len(fields) >= 3 && fields[1] == "version" {
+		version = fields[2]
+	}
+	
+	logger.Debug("ffmpeg is available: %s", version)
+	return version, nil
 }
 
 
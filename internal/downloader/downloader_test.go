@@ -0,0 +1,366 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/archive"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
+	"github.com/AlienFacepalm/YeeTrap/internal/interrupt"
+	"github.com/AlienFacepalm/YeeTrap/internal/progress"
+	"github.com/AlienFacepalm/YeeTrap/internal/retry"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+var testVideos = []youtube.Video{
+	{ID: "aaaaaaaaaaa", Title: "First"},
+	{ID: "bbbbbbbbbbb", Title: "Second"},
+	{ID: "ccccccccccc", Title: "Third"},
+	{ID: "ddddddddddd", Title: "Fourth"},
+}
+
+// newTestDownloader returns a downloader writing to a temporary directory
+// through backend, retrying without delay
+func newTestDownloader(t *testing.T, outputDir string, concurrent int, backend Backend) *Downloader {
+	t.Helper()
+
+	d, err := NewDownloader(outputDir, "best", concurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetBackend(backend)
+	d.SetProgress(progress.ModePlain, io.Discard)
+	d.retryConfig = func() *retry.RetryConfig {
+		config := retry.DownloadRetryConfig()
+		config.MaxAttempts = 3
+		config.BaseDelay = time.Millisecond
+		config.Jitter = false
+		return config
+	}
+	return d
+}
+
+// failure returns the error yt-dlp would produce for an ERROR line
+func failure(req Request, line string) error {
+	return classifyFailure("fake", req, fmt.Errorf("exit status 1"), line)
+}
+
+// countingBackend records how many downloads run at once
+type countingBackend struct {
+	*FakeBackend
+
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (b *countingBackend) Download(ctx context.Context, req Request, onProgress ProgressFunc) error {
+	b.mu.Lock()
+	b.running++
+	if b.running > b.peak {
+		b.peak = b.running
+	}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.running--
+		b.mu.Unlock()
+	}()
+	return b.FakeBackend.Download(ctx, req, onProgress)
+}
+
+func TestDownloadVideosConcurrencyLimit(t *testing.T) {
+	backend := &countingBackend{FakeBackend: &FakeBackend{Delay: 40 * time.Millisecond}}
+	d := newTestDownloader(t, t.TempDir(), 2, backend)
+
+	if err := d.DownloadVideos(context.Background(), testVideos); err != nil {
+		t.Fatalf("DownloadVideos() error = %v", err)
+	}
+
+	if backend.peak != 2 {
+		t.Errorf("peak concurrent downloads = %d, want 2", backend.peak)
+	}
+	for _, video := range testVideos {
+		if got := backend.Attempts(video.ID, ""); got != 1 {
+			t.Errorf("attempts for %s = %d, want 1", video.ID, got)
+		}
+	}
+}
+
+func TestDownloadVideosRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         string
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "transient failure then success", line: "ERROR: [download] Got error: The read operation timed out", wantAttempts: 2},
+		{name: "rate limited", line: "ERROR: [youtube] aaaaaaaaaaa: HTTP Error 429: Too Many Requests", wantAttempts: 1, wantErr: true},
+		{name: "unavailable", line: "ERROR: [youtube] aaaaaaaaaaa: Video unavailable", wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &FakeBackend{Fail: func(req Request, attempt int) error {
+				if attempt == 1 {
+					return failure(req, tt.line)
+				}
+				return nil
+			}}
+			outputDir := t.TempDir()
+			d := newTestDownloader(t, outputDir, 1, backend)
+
+			err := d.DownloadVideos(context.Background(), testVideos[:1])
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DownloadVideos() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := backend.Attempts(testVideos[0].ID, ""); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+
+			downloadArchive, err := archive.Open(outputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if downloadArchive.Has(testVideos[0].ID) == tt.wantErr {
+				t.Errorf("archived = %v, want %v", !tt.wantErr, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDownloadVideosSkipsArchived(t *testing.T) {
+	outputDir := t.TempDir()
+	if err := newTestDownloader(t, outputDir, 2, &FakeBackend{}).DownloadVideos(context.Background(), testVideos[:2]); err != nil {
+		t.Fatalf("first run error = %v", err)
+	}
+
+	backend := &FakeBackend{}
+	if err := newTestDownloader(t, outputDir, 2, backend).DownloadVideos(context.Background(), testVideos[:3]); err != nil {
+		t.Fatalf("second run error = %v", err)
+	}
+	for i, video := range testVideos[:3] {
+		want := 0
+		if i == 2 {
+			want = 1
+		}
+		if got := backend.Attempts(video.ID, ""); got != want {
+			t.Errorf("attempts for %s = %d, want %d", video.ID, got, want)
+		}
+	}
+
+	forced := &FakeBackend{}
+	d := newTestDownloader(t, outputDir, 2, forced)
+	d.SetForce(true)
+	if err := d.DownloadVideos(context.Background(), testVideos[:3]); err != nil {
+		t.Fatalf("forced run error = %v", err)
+	}
+	for _, video := range testVideos[:3] {
+		if got := forced.Attempts(video.ID, ""); got != 1 {
+			t.Errorf("forced attempts for %s = %d, want 1", video.ID, got)
+		}
+	}
+}
+
+func TestDownloadVideosMissingProfiles(t *testing.T) {
+	best, _ := format.Builtin("best")
+	podcast, err := format.Lookup("podcast", map[string]*format.Profile{
+		"podcast": {AudioOnly: true, AudioCodec: "mp3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := t.TempDir()
+	if err := newTestDownloader(t, outputDir, 1, &FakeBackend{}).DownloadVideos(context.Background(), testVideos[:2]); err != nil {
+		t.Fatalf("first run error = %v", err)
+	}
+
+	backend := &FakeBackend{}
+	d := newTestDownloader(t, outputDir, 1, backend)
+	d.SetFormats([]*format.Profile{best, podcast})
+	if err := d.DownloadVideos(context.Background(), testVideos[:2]); err != nil {
+		t.Fatalf("second run error = %v", err)
+	}
+
+	downloadArchive, err := archive.Open(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, video := range testVideos[:2] {
+		if got := backend.Attempts(video.ID, ""); got != 0 {
+			t.Errorf("main copy of %s downloaded %d times, want 0", video.ID, got)
+		}
+		if got := backend.Attempts(video.ID, "podcast"); got != 1 {
+			t.Errorf("podcast copy of %s downloaded %d times, want 1", video.ID, got)
+		}
+		if !downloadArchive.HasProfile(video.ID, "podcast") {
+			t.Errorf("podcast copy of %s not archived", video.ID)
+		}
+	}
+}
+
+func TestDownloadVideosCancellation(t *testing.T) {
+	outputDir := t.TempDir()
+	ctx, cancel, abort := interrupt.WithAbort(context.Background())
+	defer abort()
+
+	// The first download is interrupted while running and finishes; the
+	// others must not start
+	backend := &FakeBackend{Delay: 40 * time.Millisecond, Fail: func(req Request, attempt int) error {
+		cancel()
+		return nil
+	}}
+	d := newTestDownloader(t, outputDir, 1, backend)
+
+	// A complete copy of a queued video, not yet in the archive, survives
+	base, err := d.basePath(testVideos[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".mp4", []byte("complete"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.DownloadVideos(ctx, testVideos[:3]); err == nil {
+		t.Fatal("DownloadVideos() succeeded after cancellation")
+	}
+
+	if got := backend.Attempts(testVideos[0].ID, ""); got != 1 {
+		t.Errorf("attempts for the running video = %d, want 1", got)
+	}
+	for _, video := range testVideos[1:3] {
+		if got := backend.Attempts(video.ID, ""); got != 0 {
+			t.Errorf("attempts for queued %s = %d, want 0", video.ID, got)
+		}
+	}
+	if _, err := os.Stat(base + ".mp4"); err != nil {
+		t.Errorf("file of a video that never started was removed: %v", err)
+	}
+
+	entries, err := readUnfinished(UnfinishedPath(outputDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.id)
+	}
+	if want := []string{testVideos[1].ID, testVideos[2].ID}; fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("unfinished = %v, want %v", ids, want)
+	}
+
+	// Resuming finishes the rest and removes the list
+	if err := newTestDownloader(t, outputDir, 1, &FakeBackend{}).DownloadVideos(context.Background(), testVideos[1:3]); err != nil {
+		t.Fatalf("resumed run error = %v", err)
+	}
+	if _, err := os.Stat(UnfinishedPath(outputDir)); !os.IsNotExist(err) {
+		t.Errorf("unfinished list still exists after resuming: %v", err)
+	}
+}
+
+func TestDownloadVideosInterruptedBeforeStart(t *testing.T) {
+	// With free slots and an interrupt both pending, no video may start and
+	// no file of a video that never ran may be removed. Repeat, as select
+	// picks between ready cases at random.
+	for run := 0; run < 10; run++ {
+		outputDir := t.TempDir()
+		ctx, cancel, abort := interrupt.WithAbort(context.Background())
+		cancel()
+
+		backend := &FakeBackend{}
+		d := newTestDownloader(t, outputDir, len(testVideos), backend)
+
+		var files []string
+		for _, video := range testVideos {
+			base, err := d.basePath(video)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(base+".mp4", []byte("complete"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, base+".mp4")
+		}
+
+		if err := d.DownloadVideos(ctx, testVideos); err == nil {
+			t.Fatal("DownloadVideos() succeeded after an interrupt")
+		}
+		abort()
+
+		for _, file := range files {
+			if _, err := os.Stat(file); err != nil {
+				t.Fatalf("run %d removed the file of a video that never started: %v", run, err)
+			}
+		}
+		if started := d.progress.GetProgress().Finished; len(started) != 0 {
+			t.Fatalf("run %d started %d video(s) after the interrupt", run, len(started))
+		}
+
+		entries, err := readUnfinished(UnfinishedPath(outputDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != len(testVideos) {
+			t.Fatalf("run %d listed %d unfinished video(s), want %d", run, len(entries), len(testVideos))
+		}
+	}
+}
+
+func TestDownloadVideosFailureAfterInterrupt(t *testing.T) {
+	tests := []struct {
+		name      string
+		abort     bool
+		line      string
+		wantClass errors.ErrorType
+	}{
+		// Downloads allowed to finish report their real failures
+		{name: "private", line: "ERROR: [youtube] aaaaaaaaaaa: Private video", wantClass: errors.ErrorTypePrivate},
+		// A transient failure is not retried after the interrupt
+		{name: "transient", line: "ERROR: [youtube] aaaaaaaaaaa: Connection reset by peer"},
+		// A second interrupt stops the download, whatever it reports
+		{name: "aborted", abort: true, line: "ERROR: [youtube] aaaaaaaaaaa: Private video"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel, abort := interrupt.WithAbort(context.Background())
+			defer abort()
+
+			backend := &FakeBackend{Fail: func(req Request, attempt int) error {
+				cancel()
+				if tt.abort {
+					abort()
+				}
+				return failure(req, tt.line)
+			}}
+			d := newTestDownloader(t, t.TempDir(), 1, backend)
+
+			if err := d.DownloadVideos(ctx, testVideos[:2]); err == nil {
+				t.Fatal("DownloadVideos() succeeded after an interrupt")
+			}
+			if got := backend.Attempts(testVideos[0].ID, ""); got != 1 {
+				t.Errorf("attempts = %d, want 1", got)
+			}
+
+			finished := d.progress.GetProgress().Finished
+			if len(finished) != 1 {
+				t.Fatalf("finished = %v, want the first video only", finished)
+			}
+			interrupted := finished[0].Err == errInterrupted
+			if tt.wantClass == "" && !interrupted {
+				t.Errorf("error = %v, want it reported as interrupted", finished[0].Err)
+			}
+			if tt.wantClass != "" && errors.Class(finished[0].Err) != tt.wantClass {
+				t.Errorf("error = %v, want the %s failure", finished[0].Err, tt.wantClass)
+			}
+		})
+	}
+}
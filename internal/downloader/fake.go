@@ -0,0 +1,125 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+// fakeSteps is how many progress updates FakeBackend reports per download
+const fakeSteps = 4
+
+// FakeBackend writes deterministic files instead of downloading, so the
+// Downloader's concurrency, retry and archive logic can be exercised without
+// yt-dlp or a network. The zero value is ready to use.
+type FakeBackend struct {
+	// Size is the byte size of each media file; 1024 when zero
+	Size int
+	// Delay is how long each download takes
+	Delay time.Duration
+	// Fail, when set, decides whether an attempt fails; attempt counts from 1
+	// per video and format
+	Fail func(req Request, attempt int) error
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+// Name returns "fake"
+func (b *FakeBackend) Name() string {
+	return "fake"
+}
+
+// Version returns a fixed version
+func (b *FakeBackend) Version() (string, error) {
+	return "0.0.0-fake", nil
+}
+
+// Probe always succeeds
+func (b *FakeBackend) Probe(ctx context.Context) error {
+	return nil
+}
+
+// Attempts returns how many downloads of a video in a format profile were
+// attempted; profile is empty for the main copy
+func (b *FakeBackend) Attempts(videoID, profile string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempts[videoID+"/"+profile]
+}
+
+// Download writes the media file, filled with a pattern derived from the
+// video ID, and the metadata files if requested
+func (b *FakeBackend) Download(ctx context.Context, req Request, onProgress ProgressFunc) error {
+	b.mu.Lock()
+	if b.attempts == nil {
+		b.attempts = make(map[string]int)
+	}
+	key := req.Video.ID + "/" + req.Format.Key()
+	b.attempts[key]++
+	attempt := b.attempts[key]
+	b.mu.Unlock()
+
+	fmt.Fprintf(req.Log, "fake download of %s as %s, attempt %d\n", req.Video.ID, req.Format, attempt)
+	if b.Fail != nil {
+		if err := b.Fail(req, attempt); err != nil {
+			fmt.Fprintf(req.Log, "ERROR: %v\n", err)
+			return err
+		}
+	}
+
+	size := b.Size
+	if size == 0 {
+		size = 1024
+	}
+	for step := 1; step <= fakeSteps; step++ {
+		select {
+		case <-time.After(b.Delay / fakeSteps):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if onProgress != nil {
+			onProgress(Progress{Downloaded: int64(size * step / fakeSteps), Total: int64(size)})
+		}
+	}
+
+	content := bytes.Repeat([]byte(req.Video.ID), size/len(req.Video.ID)+1)[:size]
+	if err := os.WriteFile(req.OutputPath+"."+fakeExtension(req), content, 0644); err != nil {
+		return errors.WrapFile(err, "unable to write fake download")
+	}
+
+	if req.MetadataPath == "" {
+		return nil
+	}
+	info, err := json.Marshal(map[string]string{"id": req.Video.ID, "title": req.Video.Title, "_type": "video"})
+	if err != nil {
+		return errors.WrapFile(err, "unable to encode fake info JSON")
+	}
+	if err := os.WriteFile(req.MetadataPath+".info.json", info, 0644); err != nil {
+		return errors.WrapFile(err, "unable to write fake info JSON")
+	}
+	if err := os.WriteFile(req.MetadataPath+".description", []byte(req.Video.Description), 0644); err != nil {
+		return errors.WrapFile(err, "unable to write fake description")
+	}
+	return nil
+}
+
+// fakeExtension picks the file extension yt-dlp would likely produce
+func fakeExtension(req Request) string {
+	switch {
+	case req.Format.AudioOnly && req.Format.AudioCodec != "" && req.Format.AudioCodec != "best":
+		return req.Format.AudioCodec
+	case req.Format.AudioOnly:
+		return "m4a"
+	case req.Format.Container != "":
+		return req.Format.Container
+	default:
+		return "mp4"
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package downloader
+
+import "os/exec"
+
+// isolateProcess only bounds how long a cancelled yt-dlp may take to exit on
+// this platform
+func isolateProcess(cmd *exec.Cmd) {
+	cmd.WaitDelay = stopTimeout
+}
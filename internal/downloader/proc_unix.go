@@ -0,0 +1,19 @@
+//go:build unix
+
+package downloader
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolateProcess runs yt-dlp in its own process group, so a Ctrl-C in the
+// terminal reaches only YeeTrap, which decides when to stop it. Cancelling
+// the command terminates the whole group, including ffmpeg children.
+func isolateProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = stopTimeout
+}
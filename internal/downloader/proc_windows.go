@@ -0,0 +1,15 @@
+//go:build windows
+
+package downloader
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolateProcess runs yt-dlp in its own process group, so a Ctrl-C in the
+// console reaches only YeeTrap, which decides when to stop it
+func isolateProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+	cmd.WaitDelay = stopTimeout
+}
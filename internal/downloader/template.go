@@ -0,0 +1,101 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// OutputTemplate names the files of a video. It is a text/template over
+// youtube.Video; '/' in the result separates directories, and every path
+// segment is sanitized, so templated values can't escape the output directory.
+type OutputTemplate struct {
+	text string
+	tmpl *template.Template
+}
+
+// sampleVideo is used to check templates before any download starts
+var sampleVideo = youtube.Video{
+	ID:            "dQw4w9WgXcQ",
+	Title:         "Sample title",
+	PublishedAt:   time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+	ChannelID:     "UC_x5XG1OV2P6uZZ5FSM9Ttw",
+	ChannelTitle:  "Sample channel",
+	PlaylistIndex: 1,
+	Duration:      time.Minute,
+	PrivacyStatus: "public",
+}
+
+// ParseOutputTemplate parses an output template and checks that it renders
+// a usable path
+func ParseOutputTemplate(text string) (*OutputTemplate, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid output template: %v", err)).
+			WithDetails(`Example: {{.PublishedAt.Year}}/{{.PublishedAt.Format "2006-01-02"}} - {{.Title}} [{{.ID}}]`)
+	}
+
+	t := &OutputTemplate{text: text, tmpl: tmpl}
+	if err := tmpl.Execute(io.Discard, sampleVideo); err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid output template: %v", err)).
+			WithDetails("Fields are those of a video, e.g. .ID, .Title, .PublishedAt, .ChannelTitle, .PlaylistIndex")
+	}
+	if _, err := t.Render(sampleVideo); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// String returns the template text
+func (t *OutputTemplate) String() string {
+	return t.text
+}
+
+// Render returns the relative output path of a video, without extension
+func (t *OutputTemplate) Render(video youtube.Video) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, pathSafe(video)); err != nil {
+		return "", errors.NewValidationError(fmt.Sprintf("unable to render output template for video %s: %v", video.ID, err))
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(b.String(), "/") {
+		if strings.TrimSpace(segment) == "" {
+			continue
+		}
+		segments = append(segments, validation.SanitizeFilename(segment))
+	}
+	if len(segments) == 0 {
+		return "", errors.NewValidationError(fmt.Sprintf("output template %q renders an empty file name", t.text))
+	}
+	return filepath.Join(segments...), nil
+}
+
+// pathSafe returns a copy of video whose free-text fields can't add path
+// separators, so a title like "Part 1/2" stays one file name
+func pathSafe(video youtube.Video) youtube.Video {
+	replacer := strings.NewReplacer("/", "_", "\\", "_")
+	video.Title = replacer.Replace(video.Title)
+	video.Description = replacer.Replace(video.Description)
+	video.ChannelTitle = replacer.Replace(video.ChannelTitle)
+
+	tags := make([]string, len(video.Tags))
+	for i, tag := range video.Tags {
+		tags[i] = replacer.Replace(tag)
+	}
+	video.Tags = tags
+	return video
+}
+
+// escapeOutputTemplate escapes a path for use as a yt-dlp output template,
+// where '%' starts a field
+func escapeOutputTemplate(path string) string {
+	return strings.ReplaceAll(path, "%", "%%")
+}
@@ -0,0 +1,142 @@
+package downloader
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/archive"
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// stopTimeout bounds how long a cancelled yt-dlp may take to exit before it
+// is killed
+const stopTimeout = 10 * time.Second
+
+// errInterrupted marks downloads stopped by an interrupt rather than failed
+var errInterrupted = errors.NewExternalError("interrupted").WithContext(errors.ContextClass, errors.ErrorTypeInterrupted)
+
+// UnfinishedPath returns the file listing the videos interrupted runs left
+// undone. It has the --from-file format: one video ID per line, with the
+// title as a comment.
+func UnfinishedPath(outputDir string) string {
+	return filepath.Join(outputDir, constants.StateDirName, constants.UnfinishedFile)
+}
+
+// unfinishedEntry is a video listed in the unfinished file
+type unfinishedEntry struct {
+	id    string
+	title string
+}
+
+// updateUnfinished drops the completed videos from the unfinished file and
+// adds the given ones. The file is removed once nothing is left to resume.
+func updateUnfinished(path string, completed map[string]bool, unfinished []youtube.Video) error {
+	entries, err := readUnfinished(path)
+	if err != nil {
+		return err
+	}
+
+	var kept []unfinishedEntry
+	listed := make(map[string]bool)
+	for _, entry := range entries {
+		if !completed[entry.id] && !listed[entry.id] {
+			listed[entry.id] = true
+			kept = append(kept, entry)
+		}
+	}
+	for _, video := range unfinished {
+		if !listed[video.ID] {
+			listed[video.ID] = true
+			kept = append(kept, unfinishedEntry{id: video.ID, title: video.Title})
+		}
+	}
+
+	if len(kept) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.WrapFile(err, "unable to remove the list of unfinished videos")
+		}
+		return nil
+	}
+	if len(kept) == len(entries) && len(unfinished) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("# Videos an interrupted download left unfinished; resume with --resume\n")
+	for _, entry := range kept {
+		b.WriteString(entry.id)
+		if entry.title != "" {
+			fmt.Fprintf(&b, "  # %s", strings.ReplaceAll(entry.title, "\n", " "))
+		}
+		b.WriteString("\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WrapFile(err, "unable to create state directory")
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return errors.WrapFile(err, "unable to write the list of unfinished videos").WithContext("path", path)
+	}
+	return nil
+}
+
+// readUnfinished reads the unfinished file; a missing file lists nothing
+func readUnfinished(path string) ([]unfinishedEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to open the list of unfinished videos").WithContext("path", path)
+	}
+	defer file.Close()
+
+	var entries []unfinishedEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		id, title, _ := strings.Cut(scanner.Text(), "#")
+		if id = strings.TrimSpace(id); id != "" {
+			entries = append(entries, unfinishedEntry{id: id, title: strings.TrimSpace(title)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WrapFile(err, "unable to read the list of unfinished videos").WithContext("path", path)
+	}
+	return entries, nil
+}
+
+// removePartial deletes what an interrupted download in a format profile left
+// behind, unless that copy was backed up before and the files are complete
+func (d *Downloader) removePartial(video youtube.Video, profile *format.Profile, downloadArchive *archive.Archive) {
+	if downloadArchive.HasProfile(video.ID, profile.Key()) {
+		return
+	}
+	base, err := d.basePath(video)
+	if err != nil {
+		return
+	}
+
+	dir, name := filepath.Split(base + profile.Suffix())
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), name+".") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, file.Name())); err != nil {
+			logger.Warn("Unable to remove partial download %s: %v", file.Name(), err)
+		} else {
+			logger.Debug("Removed partial download %s", file.Name())
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/format"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+)
+
+// secretFlags are yt-dlp options whose values must not reach the logs
+var secretFlags = map[string]bool{
+	"-u": true, "--username": true,
+	"-p": true, "--password": true,
+	"-2": true, "--twofactor": true,
+	"--video-password":              true,
+	"--ap-username":                 true,
+	"--ap-password":                 true,
+	"--cookies":                     true,
+	"--client-certificate-key":      true,
+	"--client-certificate-password": true,
+	"--netrc-cmd":                   true,
+}
+
+// secretHeaders are --add-header headers whose values must not reach the logs
+var secretHeaders = map[string]bool{"authorization": true, "cookie": true, "proxy-authorization": true}
+
+// redactedValue replaces secrets in logged command lines
+const redactedValue = "<redacted>"
+
+// redactArgs returns args with the values of credential options, credential
+// headers and proxy passwords replaced, for logging the command line
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		// Short options may carry their value, as in -pSECRET
+		if short := args[i]; len(short) > 2 && short[0] == '-' && short[1] != '-' && secretFlags[short[:2]] {
+			redacted[i] = short[:2] + redactedValue
+			continue
+		}
+
+		flag, value, inline := strings.Cut(args[i], "=")
+		if !strings.HasPrefix(flag, "-") || (!inline && i+1 == len(args)) {
+			redacted[i] = args[i]
+			continue
+		}
+		if !inline {
+			value = args[i+1]
+		}
+
+		switch {
+		case secretFlags[flag]:
+			value = redactedValue
+		case flag == "--add-header":
+			if name, _, found := strings.Cut(value, ":"); found && secretHeaders[strings.ToLower(strings.TrimSpace(name))] {
+				value = name + ":" + redactedValue
+			}
+		case flag == "--proxy" || flag == "--geo-verification-proxy":
+			if u, err := url.Parse(value); err == nil {
+				value = u.Redacted()
+			}
+		default:
+			redacted[i] = args[i]
+			continue
+		}
+
+		if inline {
+			redacted[i] = flag + "=" + value
+		} else {
+			redacted[i] = flag
+			redacted[i+1] = value
+			i++
+		}
+	}
+	return redacted
+}
+
+// YtDlp is the backend running yt-dlp, or youtube-dl in legacy mode
+type YtDlp struct {
+	name      string
+	binary    string
+	extraArgs []string
+	// legacy marks youtube-dl, which lacks progress templates, per-type
+	// output paths, regex format filters and remuxing
+	legacy bool
+}
+
+// NewYtDlp returns a yt-dlp backend. binary defaults to "yt-dlp" on the PATH;
+// extraArgs are added to every download.
+func NewYtDlp(binary string, extraArgs []string) *YtDlp {
+	if binary == "" {
+		binary = constants.BackendYtDlp
+	}
+	return &YtDlp{name: constants.BackendYtDlp, binary: binary, extraArgs: extraArgs}
+}
+
+// NewYoutubeDL returns a youtube-dl backend, for systems without yt-dlp.
+// binary defaults to "youtube-dl" on the PATH.
+func NewYoutubeDL(binary string, extraArgs []string) *YtDlp {
+	if binary == "" {
+		binary = constants.BackendYoutubeDL
+	}
+	return &YtDlp{name: constants.BackendYoutubeDL, binary: binary, extraArgs: extraArgs, legacy: true}
+}
+
+// Name returns "yt-dlp" or "youtube-dl"
+func (b *YtDlp) Name() string {
+	return b.name
+}
+
+// Version returns the installed version
+func (b *YtDlp) Version() (string, error) {
+	return b.version(context.Background())
+}
+
+// Probe checks that the binary runs
+func (b *YtDlp) Probe(ctx context.Context) error {
+	_, err := b.version(ctx)
+	return err
+}
+
+// version runs the binary with --version
+func (b *YtDlp) version(ctx context.Context) (string, error) {
+	logger.Debug("Checking if %s is available", b.binary)
+
+	out, err := exec.CommandContext(ctx, b.binary, "--version").Output()
+	if err != nil {
+		details := "Please install it from https://github.com/yt-dlp/yt-dlp"
+		if b.legacy {
+			details = "Please install yt-dlp from https://github.com/yt-dlp/yt-dlp"
+		}
+		return "", errors.NewExternalError(fmt.Sprintf("%s is not installed or not in PATH", b.binary)).
+			WithDetails(details)
+	}
+
+	version := strings.TrimSpace(string(out))
+	logger.Debug("%s is available: %s", b.binary, version)
+	return version, nil
+}
+
+// Download runs one download, feeding progress lines to onProgress and
+// copying all other output to req.Log. Failures are classified from the
+// error output, see classifyFailure.
+func (b *YtDlp) Download(ctx context.Context, req Request, onProgress ProgressFunc) error {
+	args, err := b.args(req)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(req.Log, "$ %s %s\n", b.binary, strings.Join(redactArgs(args), " "))
+
+	cmd := exec.CommandContext(ctx, b.binary, args...)
+	isolateProcess(cmd)
+	stderr := newTailBuffer(stderrTailLines)
+	cmd.Stderr = io.MultiWriter(req.Log, stderr)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.WrapExternal(err, fmt.Sprintf("unable to capture %s output", b.name))
+	}
+
+	if err := cmd.Start(); err != nil {
+		return errors.WrapExternal(err, fmt.Sprintf("unable to start %s", b.binary))
+	}
+
+	parse := parseProgressLine
+	if b.legacy {
+		parse = parseLegacyProgressLine
+	}
+	consumeOutput(stdout, req.Log, parse, onProgress)
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return classifyFailure(b.name, req, err, stderr.String())
+	}
+	return nil
+}
+
+// args builds the command line of a download
+func (b *YtDlp) args(req Request) ([]string, error) {
+	if b.legacy {
+		if err := legacyCompatible(req.Format); err != nil {
+			return nil, err
+		}
+	}
+
+	args := append(req.Format.Args(),
+		"-o", escapeOutputTemplate(req.OutputPath)+".%(ext)s",
+		"--no-playlist",
+		"--no-warnings",
+	)
+
+	switch {
+	case req.MetadataPath == "":
+	case req.MetadataPath == req.OutputPath:
+		args = append(args, "--write-description", "--write-info-json", "--write-thumbnail")
+	case b.legacy:
+		// youtube-dl can only write metadata next to the media
+		logger.Debug("Skipping metadata of %s: youtube-dl cannot write it to %s", req.Video.ID, req.MetadataPath)
+	default:
+		args = append(args, "--write-description", "--write-info-json", "--write-thumbnail")
+		metadataPath := escapeOutputTemplate(req.MetadataPath) + ".%(ext)s"
+		for _, kind := range []string{"description", "infojson", "thumbnail"} {
+			args = append(args, "-o", kind+":"+metadataPath)
+		}
+	}
+
+	if b.legacy {
+		args = append(args, "--newline")
+	} else {
+		args = append(args, progressArgs...)
+	}
+	args = append(args, b.extraArgs...)
+	return append(args, req.URL()), nil
+}
+
+// legacyCompatible rejects format profiles youtube-dl cannot carry out
+func legacyCompatible(profile *format.Profile) error {
+	if len(profile.Codecs) > 0 || profile.HDR != format.HDRAny || profile.Container != "" {
+		return errors.NewValidationError(fmt.Sprintf("format profile %s needs yt-dlp: youtube-dl supports no codecs, hdr or container settings", profile))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package downloader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// progressPrefix marks the machine-readable progress lines we ask yt-dlp for
+const progressPrefix = "yeetrap-progress"
+
+// progressTemplate makes yt-dlp print one parseable line per progress update.
+// Missing values are printed as "NA".
+var progressTemplate = "download:" + progressPrefix +
+	" %(progress.downloaded_bytes)s %(progress.total_bytes)s %(progress.total_bytes_estimate)s" +
+	" %(progress.speed)s %(progress.eta)s"
+
+// progressArgs are the yt-dlp arguments producing progress lines on stdout
+var progressArgs = []string{"--newline", "--progress-template", progressTemplate}
+
+// parseProgressLine parses a progress line printed with progressTemplate.
+// Lines whose downloaded bytes are "NA" are recognized but carry no progress.
+func parseProgressLine(line string) (Progress, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 6 || fields[0] != progressPrefix {
+		return Progress{}, false
+	}
+
+	downloaded, ok := parseNumber(fields[1])
+	if !ok {
+		return Progress{}, true
+	}
+
+	p := Progress{Downloaded: int64(downloaded)}
+	if total, ok := parseNumber(fields[2]); ok {
+		p.Total = int64(total)
+	} else if estimate, ok := parseNumber(fields[3]); ok {
+		// Fragmented downloads only know an estimate
+		p.Total = int64(estimate)
+	}
+	if speed, ok := parseNumber(fields[4]); ok {
+		p.Speed = speed
+	}
+	if eta, ok := parseNumber(fields[5]); ok {
+		p.ETA = time.Duration(eta * float64(time.Second))
+	}
+	return p, true
+}
+
+// parseNumber parses a yt-dlp numeric field, which may be "NA" or "None"
+func parseNumber(s string) (float64, bool) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseLegacyProgressLine parses a youtube-dl progress line such as
+// "[download]  42.0% of 12.34MiB at  1.23MiB/s ETA 00:07". Fragmented
+// downloads print an estimated total ("of ~12.34MiB"), downloads of unknown
+// size the bytes so far ("1.23MiB at 456.78KiB/s") and some only a percentage,
+// which gives no byte counts.
+func parseLegacyProgressLine(line string) (Progress, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "[download]" {
+		return Progress{}, false
+	}
+
+	var p Progress
+	rest := fields[2:]
+	if value, found := strings.CutSuffix(fields[1], "%"); found {
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Progress{}, false
+		}
+		if len(rest) >= 2 && rest[0] == "of" {
+			total, ok := parseSize(strings.TrimPrefix(rest[1], "~"))
+			if !ok {
+				return Progress{}, false
+			}
+			p.Downloaded = int64(float64(total) * percent / 100)
+			p.Total = total
+			rest = rest[2:]
+		}
+	} else if downloaded, ok := parseSize(fields[1]); ok && len(rest) > 0 && rest[0] == "at" {
+		p.Downloaded = downloaded
+	} else {
+		return Progress{}, false
+	}
+
+	for i := 0; i+1 < len(rest); i++ {
+		switch rest[i] {
+		case "at":
+			if speed, ok := parseSize(strings.TrimSuffix(rest[i+1], "/s")); ok {
+				p.Speed = float64(speed)
+			}
+		case "ETA":
+			p.ETA = parseClock(rest[i+1])
+		}
+	}
+	return p, true
+}
+
+// sizeUnits are the binary size suffixes youtube-dl prints
+var sizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40}, {"B", 1},
+}
+
+// parseSize parses a size like "12.34MiB"
+func parseSize(s string) (int64, bool) {
+	for _, unit := range sizeUnits {
+		if value, found := strings.CutSuffix(s, unit.suffix); found {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			return int64(n * unit.factor), true
+		}
+	}
+	return 0, false
+}
+
+// parseClock parses a duration like "00:07" or "1:02:03"
+func parseClock(s string) time.Duration {
+	var seconds int
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// consumeOutput feeds progress lines to onProgress and copies every other
+// line to the log. Progress lines without any values are dropped. The output
+// is always read to the end, even past a line too long to scan, so the
+// process never blocks on a full pipe.
+func consumeOutput(r io.Reader, log io.Writer, parse func(string) (Progress, bool), onProgress ProgressFunc) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if p, ok := parse(line); ok {
+			if onProgress != nil && p != (Progress{}) {
+				onProgress(p)
+			}
+			continue
+		}
+		fmt.Fprintln(log, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(log, "yeetrap: unable to read further output: %v\n", err)
+		io.Copy(io.Discard, r)
+	}
+}
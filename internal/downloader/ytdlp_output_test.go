@@ -0,0 +1,188 @@
+package downloader
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   Progress
+		wantOK bool
+	}{
+		{
+			name:   "complete",
+			line:   "yeetrap-progress 1048576 4194304 NA 524288.5 6",
+			want:   Progress{Downloaded: 1048576, Total: 4194304, Speed: 524288.5, ETA: 6 * time.Second},
+			wantOK: true,
+		},
+		{
+			name:   "fragments with estimated total",
+			line:   "yeetrap-progress 2097152 NA 52428800.0 1048576.0 48",
+			want:   Progress{Downloaded: 2097152, Total: 52428800, Speed: 1048576, ETA: 48 * time.Second},
+			wantOK: true,
+		},
+		{
+			name:   "total preferred over estimate",
+			line:   "yeetrap-progress 1024 4096 8192 NA NA",
+			want:   Progress{Downloaded: 1024, Total: 4096},
+			wantOK: true,
+		},
+		{
+			name:   "unknown total and speed",
+			line:   "yeetrap-progress 1024 NA NA NA NA",
+			want:   Progress{Downloaded: 1024},
+			wantOK: true,
+		},
+		{
+			name:   "unknown downloaded bytes",
+			line:   "yeetrap-progress NA 4096 NA 1024 3",
+			wantOK: true,
+		},
+		{name: "destination", line: "[download] Destination: Title [dQw4w9WgXcQ].f137.mp4"},
+		{name: "yt-dlp progress", line: "[download]  42.0% of   12.34MiB at    1.23MiB/s ETA 00:07"},
+		{name: "merger", line: `[Merger] Merging formats into "Title [dQw4w9WgXcQ].mp4"`},
+		{name: "missing fields", line: "yeetrap-progress 1024 4096"},
+		{name: "other prefix", line: "progress 1024 4096 NA NA NA"},
+		{name: "empty", line: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProgressLine(tt.line)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseProgressLine() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseLegacyProgressLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   Progress
+		wantOK bool
+	}{
+		{
+			name:   "complete",
+			line:   "[download]  50.0% of 10.00MiB at  1.00MiB/s ETA 00:05",
+			want:   Progress{Downloaded: 5 << 20, Total: 10 << 20, Speed: 1 << 20, ETA: 5 * time.Second},
+			wantOK: true,
+		},
+		{
+			name:   "fragments with estimated total",
+			line:   "[download]  25.0% of ~4.00GiB at  2.00MiB/s ETA 1:02:03 (frag 3/24)",
+			want:   Progress{Downloaded: 1 << 30, Total: 4 << 30, Speed: 2 << 20, ETA: time.Hour + 2*time.Minute + 3*time.Second},
+			wantOK: true,
+		},
+		{
+			name:   "finished",
+			line:   "[download] 100% of 512.00KiB in 00:02",
+			want:   Progress{Downloaded: 512 << 10, Total: 512 << 10},
+			wantOK: true,
+		},
+		{
+			name:   "unknown total",
+			line:   "[download]    1.50MiB at  512.00KiB/s (00:03)",
+			want:   Progress{Downloaded: 3 << 19, Speed: 512 << 10},
+			wantOK: true,
+		},
+		{
+			name:   "percentage only",
+			line:   "[download]  42.0% at  1.00MiB/s ETA 00:07",
+			want:   Progress{Speed: 1 << 20, ETA: 7 * time.Second},
+			wantOK: true,
+		},
+		{
+			name:   "bare percentage",
+			line:   "[download]  42.0%",
+			wantOK: true,
+		},
+		{
+			name:   "unknown speed and ETA",
+			line:   "[download]  10.0% of 1.00KiB at Unknown speed ETA Unknown ETA",
+			want:   Progress{Downloaded: 102, Total: 1 << 10},
+			wantOK: true,
+		},
+		{name: "destination", line: "[download] Destination: Title-dQw4w9WgXcQ.mp4"},
+		{name: "already downloaded", line: "[download] Title-dQw4w9WgXcQ.mp4 has already been downloaded"},
+		{name: "resuming", line: "[download] Resuming download at byte 1024"},
+		{name: "unknown size", line: "[download]  42.0% of Unknown size"},
+		{name: "extractor", line: "[youtube] dQw4w9WgXcQ: Downloading webpage"},
+		{name: "ffmpeg", line: "[ffmpeg] Merging formats into \"Title-dQw4w9WgXcQ.mp4\""},
+		{name: "template line", line: "yeetrap-progress 1024 4096 NA NA NA"},
+		{name: "tag only", line: "[download]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseLegacyProgressLine(tt.line)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseLegacyProgressLine() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestConsumeOutput(t *testing.T) {
+	output := strings.Join([]string{
+		"[youtube] dQw4w9WgXcQ: Downloading webpage",
+		"yeetrap-progress NA NA NA NA NA",
+		"yeetrap-progress 1024 4096 NA NA NA",
+		"[download] Destination: Title [dQw4w9WgXcQ].mp4",
+		"yeetrap-progress 4096 4096 NA NA 0",
+	}, "\n")
+
+	var log strings.Builder
+	var updates []Progress
+	consumeOutput(strings.NewReader(output), &log, parseProgressLine, func(p Progress) {
+		updates = append(updates, p)
+	})
+
+	want := []Progress{{Downloaded: 1024, Total: 4096}, {Downloaded: 4096, Total: 4096}}
+	if !reflect.DeepEqual(updates, want) {
+		t.Errorf("progress updates = %+v, want %+v", updates, want)
+	}
+	wantLog := "[youtube] dQw4w9WgXcQ: Downloading webpage\n[download] Destination: Title [dQw4w9WgXcQ].mp4\n"
+	if log.String() != wantLog {
+		t.Errorf("log = %q, want %q", log.String(), wantLog)
+	}
+}
+
+func TestConsumeOutputLongLine(t *testing.T) {
+	// A pipe blocks the writer until everything is read, like yt-dlp's stdout
+	r, w := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		_, err := io.WriteString(w, "yeetrap-progress 1024 4096 NA NA NA\n"+strings.Repeat("x", 2<<20)+"\nyeetrap-progress 4096 4096 NA NA NA\n")
+		w.Close()
+		written <- err
+	}()
+
+	var log strings.Builder
+	var updates []Progress
+	consumeOutput(r, &log, parseProgressLine, func(p Progress) {
+		updates = append(updates, p)
+	})
+
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("write error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("output after the long line was not read")
+	}
+	if len(updates) != 1 {
+		t.Errorf("progress updates = %+v, want the one before the long line", updates)
+	}
+	if !strings.Contains(log.String(), "token too long") {
+		t.Errorf("log = %q, want the scan error", log.String())
+	}
+}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"strings"
 )
@@ -18,6 +19,26 @@ const (
 	ErrorTypeExternal  ErrorType = "external"
 )
 
+// Download failure kinds, classified from yt-dlp's error output
+const (
+	ErrorTypeRateLimited  ErrorType = "rate-limited"
+	ErrorTypeUnavailable  ErrorType = "unavailable"
+	ErrorTypePrivate      ErrorType = "private"
+	ErrorTypeAuthRequired ErrorType = "auth-required"
+	ErrorTypeGeoBlocked   ErrorType = "geo-blocked"
+	ErrorTypeDiskFull     ErrorType = "disk-full"
+	ErrorTypeTransient    ErrorType = "transient"
+	// ErrorTypeInterrupted marks downloads an interrupt stopped or kept from
+	// starting
+	ErrorTypeInterrupted ErrorType = "interrupted"
+)
+
+// Context keys of classified download failures
+const (
+	ContextClass      = "class"
+	ContextStderrTail = "stderr_tail"
+)
+
 // YeeTrapError represents a custom error with additional context
 type YeeTrapError struct {
 	Type    ErrorType
@@ -152,6 +173,30 @@ func GetErrorType(err error) ErrorType {
 	return ""
 }
 
+// Is reports whether err or an error it wraps matches target, like the
+// standard library's errors.Is
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// Class returns the failure class recorded on err or an error it wraps, or
+// "" if there is none
+func Class(err error) ErrorType {
+	for err != nil {
+		if ytErr, ok := err.(*YeeTrapError); ok {
+			if class, ok := ytErr.Context[ContextClass].(ErrorType); ok {
+				return class
+			}
+		}
+		unwrapper, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return ""
+		}
+		err = unwrapper.Unwrap()
+	}
+	return ""
+}
+
 // FormatError formats an error for user display
 func FormatError(err error) string {
 	if ytErr, ok := err.(*YeeTrapError); ok {
@@ -196,6 +241,20 @@ func GetUserFriendlyMessage(err error) string {
 			return "Invalid input. Please check your command parameters."
 		case ErrorTypeExternal:
 			return "External tool error. Please ensure yt-dlp is installed and accessible."
+		case ErrorTypeRateLimited:
+			return "YouTube is rate limiting downloads. Please wait a while before trying again."
+		case ErrorTypeUnavailable:
+			return "The video is no longer available on YouTube."
+		case ErrorTypePrivate:
+			return "The video is private."
+		case ErrorTypeAuthRequired:
+			return "The video requires a signed-in account."
+		case ErrorTypeGeoBlocked:
+			return "The video is not available in your country."
+		case ErrorTypeDiskFull:
+			return "The disk is full. Please free up space and try again."
+		case ErrorTypeTransient:
+			return "A temporary network or server error occurred. Please try again."
 		default:
 			return ytErr.Message
 		}
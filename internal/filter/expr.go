@@ -0,0 +1,563 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// kind is the type of a value in a filter expression
+type kind int
+
+const (
+	kindBool kind = iota
+	kindNumber
+	kindDuration
+	kindTime
+	kindString
+	kindList
+)
+
+// String returns the name of the kind as shown in error messages
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "boolean"
+	case kindNumber:
+		return "number"
+	case kindDuration:
+		return "duration"
+	case kindTime:
+		return "date"
+	case kindString:
+		return "string"
+	default:
+		return "list"
+	}
+}
+
+// value is a typed value produced while evaluating an expression
+type value struct {
+	b bool
+	n int64
+	d time.Duration
+	t time.Time
+	s string
+	l []string
+}
+
+// node is a compiled expression: its static type and an evaluator
+type node struct {
+	kind kind
+	eval func(*youtube.Video) value
+	// literal is set for constants, which comparisons may inspect at compile time
+	literal *value
+}
+
+// field describes a video attribute usable in expressions
+type field struct {
+	kind kind
+	get  func(*youtube.Video) value
+}
+
+// fields are the video attributes available in filter expressions
+var fields = map[string]field{
+	"id":            {kindString, func(v *youtube.Video) value { return value{s: v.ID} }},
+	"title":         {kindString, func(v *youtube.Video) value { return value{s: v.Title} }},
+	"description":   {kindString, func(v *youtube.Video) value { return value{s: v.Description} }},
+	"channel":       {kindString, func(v *youtube.Video) value { return value{s: v.ChannelTitle} }},
+	"published":     {kindTime, func(v *youtube.Video) value { return value{t: v.PublishedAt} }},
+	"duration":      {kindDuration, func(v *youtube.Video) value { return value{d: v.Duration} }},
+	"privacy":       {kindString, func(v *youtube.Video) value { return value{s: v.PrivacyStatus} }},
+	"upload_status": {kindString, func(v *youtube.Video) value { return value{s: v.UploadStatus} }},
+	"tags":          {kindList, func(v *youtube.Video) value { return value{l: v.Tags} }},
+	"category":      {kindString, func(v *youtube.Video) value { return value{s: v.CategoryID} }},
+	"language":      {kindString, func(v *youtube.Video) value { return value{s: v.DefaultLanguage} }},
+	"definition":    {kindString, func(v *youtube.Video) value { return value{s: v.Definition} }},
+	"captions":      {kindBool, func(v *youtube.Video) value { return value{b: v.HasCaptions} }},
+	"live":          {kindString, func(v *youtube.Video) value { return value{s: v.LiveBroadcastContent} }},
+	"views":         {kindNumber, func(v *youtube.Video) value { return value{n: int64(v.ViewCount)} }},
+	"likes":         {kindNumber, func(v *youtube.Video) value { return value{n: int64(v.LikeCount)} }},
+	"position":      {kindNumber, func(v *youtube.Video) value { return value{n: int64(v.PlaylistIndex)} }},
+	"short":         {kindBool, func(v *youtube.Video) value { return value{b: IsShort(*v)} }},
+}
+
+// FieldNames returns the names of the fields available in expressions
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// token types
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokLiteral
+	tokOp
+)
+
+// comparisonOps are the binary comparison operators
+var comparisonOps = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "=~": true, "!~": true,
+}
+
+// token is a lexical token of a filter expression
+type token struct {
+	typ  int
+	text string
+	// pos is the rune offset of the token in the expression
+	pos int
+}
+
+// operators, longest first so two-character operators win
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+// lex splits an expression into tokens. Token positions count runes, so
+// error columns stay right after non-ASCII text.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	column := func(i int) int { return utf8.RuneCountInString(input[:i]) }
+	i := 0
+
+	for i < len(input) {
+		c, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '"' || c == '\'':
+			end := i + size
+			for end < len(input) {
+				r, n := utf8.DecodeRuneInString(input[end:])
+				if r == c {
+					break
+				}
+				end += n
+				if r == '\\' && c == '"' && end < len(input) {
+					_, n = utf8.DecodeRuneInString(input[end:])
+					end += n
+				}
+			}
+			if end >= len(input) {
+				return nil, exprError(column(i), "unterminated string")
+			}
+			text, err := unquote(input[i : end+1])
+			if err != nil {
+				return nil, exprError(column(i), "invalid string: %v", err)
+			}
+			tokens = append(tokens, token{tokString, text, column(i)})
+			i = end + 1
+		case unicode.IsDigit(c):
+			end := scanWhile(input, i, isLiteralChar)
+			tokens = append(tokens, token{tokLiteral, input[i:end], column(i)})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := scanWhile(input, i, isIdentChar)
+			tokens = append(tokens, token{tokIdent, input[i:end], column(i)})
+			i = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(input[i:], op) {
+					tokens = append(tokens, token{tokOp, op, column(i)})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, exprError(column(i), "unexpected character %q", c)
+			}
+		}
+	}
+
+	return append(tokens, token{tokEOF, "", column(len(input))}), nil
+}
+
+// scanWhile returns the offset of the first rune from start on that is not ok
+func scanWhile(input string, start int, ok func(rune) bool) int {
+	end := start
+	for end < len(input) {
+		r, n := utf8.DecodeRuneInString(input[end:])
+		if !ok(r) {
+			break
+		}
+		end += n
+	}
+	return end
+}
+
+// isIdentChar reports whether c may appear in a field name
+func isIdentChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// isLiteralChar reports whether c may appear in a number, duration or date
+func isLiteralChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '.' || c == ':' || c == '-' || c == '+'
+}
+
+// unquote decodes a string literal. Double-quoted strings use Go escapes;
+// single-quoted strings are raw, which suits regular expressions.
+func unquote(s string) (string, error) {
+	if s[0] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	return strconv.Unquote(s)
+}
+
+// parser is a recursive-descent parser producing compiled nodes. Grammar:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | comparison
+//	comparison = operand [ op operand ]
+//	operand    = "(" or ")" | field | string | number | duration | date
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Compile parses an expression into a video filter
+func Compile(expr string) (youtube.VideoFilter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.typ != tokEOF {
+		return nil, exprError(tok.pos, "unexpected %q", tok.text)
+	}
+	if n.kind != kindBool {
+		return nil, exprError(0, "expression must be a condition, not a %s", n.kind)
+	}
+
+	return func(video youtube.Video) bool {
+		return n.eval(&video).b
+	}, nil
+}
+
+// peek returns the current token
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next consumes and returns the current token
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.typ != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptOp consumes the current token if it is the given operator
+func (p *parser) acceptOp(op string) bool {
+	if tok := p.peek(); tok.typ == tokOp && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseOr parses a disjunction
+func (p *parser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().typ == tokOp && p.peek().text == "||" {
+		tok := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool(tok, left, right); err != nil {
+			return nil, err
+		}
+		l, r := left.eval, right.eval
+		left = &node{kind: kindBool, eval: func(v *youtube.Video) value {
+			return value{b: l(v).b || r(v).b}
+		}}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses a conjunction
+func (p *parser) parseAnd() (*node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().typ == tokOp && p.peek().text == "&&" {
+		tok := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool(tok, left, right); err != nil {
+			return nil, err
+		}
+		l, r := left.eval, right.eval
+		left = &node{kind: kindBool, eval: func(v *youtube.Video) value {
+			return value{b: l(v).b && r(v).b}
+		}}
+	}
+
+	return left, nil
+}
+
+// parseUnary parses a negation or a comparison
+func (p *parser) parseUnary() (*node, error) {
+	if tok := p.peek(); tok.typ == tokOp && tok.text == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool(tok, operand); err != nil {
+			return nil, err
+		}
+		eval := operand.eval
+		return &node{kind: kindBool, eval: func(v *youtube.Video) value {
+			return value{b: !eval(v).b}
+		}}, nil
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison parses an operand optionally compared with another
+func (p *parser) parseComparison() (*node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	isComparison := tok.typ == tokOp && comparisonOps[tok.text]
+	isContains := tok.typ == tokIdent && tok.text == "contains"
+	if !isComparison && !isContains {
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return compare(tok, left, right)
+}
+
+// parseOperand parses a parenthesized expression, field or literal
+func (p *parser) parseOperand() (*node, error) {
+	tok := p.next()
+
+	switch tok.typ {
+	case tokOp:
+		if tok.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.acceptOp(")") {
+				return nil, exprError(p.peek().pos, "expected \")\"")
+			}
+			return n, nil
+		}
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return constant(kindBool, value{b: tok.text == "true"}), nil
+		}
+		f, ok := fields[tok.text]
+		if !ok {
+			return nil, exprError(tok.pos, "unknown field %q (available: %s)", tok.text, strings.Join(FieldNames(), ", "))
+		}
+		return &node{kind: f.kind, eval: f.get}, nil
+	case tokString:
+		return constant(kindString, value{s: tok.text}), nil
+	case tokLiteral:
+		return parseLiteral(tok)
+	case tokEOF:
+		return nil, exprError(tok.pos, "unexpected end of expression")
+	}
+
+	return nil, exprError(tok.pos, "unexpected %q", tok.text)
+}
+
+// parseLiteral classifies a literal as a date, number or duration
+func parseLiteral(tok token) (*node, error) {
+	if t, err := parseDate(tok.text); err == nil {
+		return constant(kindTime, value{t: t}), nil
+	}
+	if n, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+		return constant(kindNumber, value{n: n}), nil
+	}
+	if d, err := ParseDuration(tok.text); err == nil {
+		return constant(kindDuration, value{d: d}), nil
+	}
+	return nil, exprError(tok.pos, "invalid literal %q: expected a number, duration (10m, 1h30m, 2d) or date (2024-01-31)", tok.text)
+}
+
+// constant returns a node that always evaluates to v
+func constant(k kind, v value) *node {
+	return &node{kind: k, eval: func(*youtube.Video) value { return v }, literal: &v}
+}
+
+// compare builds a comparison node, checking operand types
+func compare(op token, left, right *node) (*node, error) {
+	// A bare number next to a duration counts seconds: duration > 600
+	if left.kind == kindDuration && right.kind == kindNumber && right.literal != nil {
+		right = constant(kindDuration, value{d: time.Duration(right.literal.n) * time.Second})
+	}
+	if right.kind == kindDuration && left.kind == kindNumber && left.literal != nil {
+		left = constant(kindDuration, value{d: time.Duration(left.literal.n) * time.Second})
+	}
+
+	l, r := left.eval, right.eval
+	boolNode := func(f func(v *youtube.Video) bool) *node {
+		return &node{kind: kindBool, eval: func(v *youtube.Video) value { return value{b: f(v)} }}
+	}
+
+	switch op.text {
+	case "=~", "!~", "contains":
+		if right.kind != kindString || right.literal == nil {
+			return nil, exprError(op.pos, "%s needs a string literal on the right", op.text)
+		}
+		if left.kind != kindString && left.kind != kindList {
+			return nil, exprError(op.pos, "%s cannot be applied to a %s", op.text, left.kind)
+		}
+
+		var match func(string) bool
+		if op.text == "contains" {
+			needle := strings.ToLower(right.literal.s)
+			if left.kind == kindList {
+				match = func(s string) bool { return strings.ToLower(s) == needle }
+			} else {
+				match = func(s string) bool { return strings.Contains(strings.ToLower(s), needle) }
+			}
+		} else {
+			re, err := regexp.Compile(right.literal.s)
+			if err != nil {
+				return nil, exprError(op.pos, "invalid regular expression: %v", err)
+			}
+			match = re.MatchString
+		}
+
+		negate := op.text == "!~"
+		return boolNode(func(v *youtube.Video) bool {
+			lv := l(v)
+			found := false
+			if left.kind == kindList {
+				for _, item := range lv.l {
+					if match(item) {
+						found = true
+						break
+					}
+				}
+			} else {
+				found = match(lv.s)
+			}
+			return found != negate
+		}), nil
+	}
+
+	if left.kind != right.kind {
+		return nil, exprError(op.pos, "cannot compare %s with %s", left.kind, right.kind)
+	}
+
+	ordered := op.text != "==" && op.text != "!="
+	if ordered && (left.kind == kindBool || left.kind == kindList) {
+		return nil, exprError(op.pos, "%s cannot be applied to a %s", op.text, left.kind)
+	}
+	if left.kind == kindList {
+		return nil, exprError(op.pos, "lists can only be matched with contains, =~ or !~")
+	}
+
+	k := left.kind
+	return boolNode(func(v *youtube.Video) bool {
+		c := compareValues(k, l(v), r(v))
+		switch op.text {
+		case "==":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}), nil
+}
+
+// compareValues returns -1, 0 or 1 comparing two values of the same kind
+func compareValues(k kind, a, b value) int {
+	switch k {
+	case kindBool:
+		if a.b == b.b {
+			return 0
+		}
+		return 1
+	case kindNumber:
+		return cmp(a.n, b.n)
+	case kindDuration:
+		return cmp(a.d, b.d)
+	case kindTime:
+		return a.t.Compare(b.t)
+	default:
+		return strings.Compare(a.s, b.s)
+	}
+}
+
+// cmp compares two ordered values
+func cmp[T int64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// requireBool checks that the operands of a logical operator are conditions
+func requireBool(op token, operands ...*node) error {
+	for _, operand := range operands {
+		if operand.kind != kindBool {
+			return exprError(op.pos, "%s needs conditions, not a %s", op.text, operand.kind)
+		}
+	}
+	return nil
+}
+
+// exprError returns a validation error pointing at a column of the expression
+func exprError(pos int, format string, args ...interface{}) error {
+	return errors.NewValidationError(fmt.Sprintf("invalid filter expression at column %d: %s", pos+1, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,132 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+var exprVideo = youtube.Video{
+	ID:            "dQw4w9WgXcQ",
+	Title:         "Ünïcode — 日本語 vlog",
+	PublishedAt:   time.Date(2024, 2, 10, 12, 0, 0, 0, time.UTC),
+	Duration:      12 * time.Minute,
+	PrivacyStatus: "public",
+	Tags:          []string{"go", "vlog"},
+	HasCaptions:   true,
+	ViewCount:     1500,
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// Durations, with bare numbers counting seconds
+		{`duration > 10m`, true},
+		{`duration < 10m`, false},
+		{`duration >= 1h`, false},
+		{`duration > 600`, true},
+		{`duration <= 1d`, true},
+
+		// Dates
+		{`published >= 2024-01-31`, true},
+		{`published < 2024-01-31`, false},
+		{`published > 2024-02-10T11:00:00Z`, true},
+
+		// Numbers
+		{`views > 1000`, true},
+		{`views == 1500`, true},
+		{`views != 1500`, false},
+		{`views <= 1499`, false},
+
+		// Strings, including non-ASCII text
+		{`title == "Ünïcode — 日本語 vlog"`, true},
+		{`title != 'Ünïcode'`, true},
+		{`title contains "日本語"`, true},
+		{`title contains "ÜNÏCODE"`, true},
+		{`title =~ '^Ü.*vlog$'`, true},
+		{`title !~ 'vlog'`, false},
+		{`privacy == "public"`, true},
+		{`privacy < "public"`, false},
+		{`title == "a\"b"`, false},
+		{`tags contains "GO"`, true},
+		{`tags =~ '^vl'`, true},
+
+		// && binds tighter than ||
+		{`false && false || true`, true},
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`false && (false || true)`, false},
+
+		// ! binds tightest
+		{`!captions`, false},
+		{`!!captions`, true},
+		{`!captions || views > 1000`, true},
+		{`!(captions && views > 2000)`, true},
+		{`!(views > 2000) && short == false`, true},
+
+		// Nested parentheses
+		{`((views > 1000 && (duration > 10m || short)) && !(privacy == "private"))`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			keep, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got := keep(exprVideo); got != tt.want {
+				t.Errorf("Compile(%s) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		column  int
+		message string
+	}{
+		{`views >`, 8, "unexpected end of expression"},
+		{`views > 10 &&`, 14, "unexpected end of expression"},
+		{`(views > 10`, 12, `expected ")"`},
+		{`views > 10)`, 11, `unexpected ")"`},
+		{`title == "abc`, 10, "unterminated string"},
+		{`title == "\q"`, 10, "invalid string"},
+		{`views # 10`, 7, "unexpected character '#'"},
+		{`views > 1 ☃`, 11, "unexpected character '☃'"},
+		{`unknown > 3`, 1, `unknown field "unknown"`},
+		{`title == "日本" && viewz > 1`, 18, `unknown field "viewz"`},
+		{`views > "x"`, 7, "cannot compare number with string"},
+		{`views > 10m`, 7, "cannot compare number with duration"},
+		{`published > 1500`, 11, "cannot compare date with number"},
+		{`duration > 10x`, 12, "invalid literal"},
+		{`title`, 1, "must be a condition"},
+		{`views && captions`, 7, "&& needs conditions"},
+		{`!title`, 1, "! needs conditions"},
+		{`captions > true`, 10, "> cannot be applied to a boolean"},
+		{`title =~ '('`, 7, "invalid regular expression"},
+		{`title =~ title`, 7, "needs a string literal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			if err == nil {
+				t.Fatalf("Compile(%s) succeeded, want an error", tt.expr)
+			}
+			msg := err.Error()
+			if want := fmt.Sprintf("at column %d:", tt.column); !strings.Contains(msg, want) {
+				t.Errorf("error %q does not point at column %d", msg, tt.column)
+			}
+			if !strings.Contains(msg, tt.message) {
+				t.Errorf("error %q does not mention %q", msg, tt.message)
+			}
+		})
+	}
+}
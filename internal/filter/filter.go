@@ -0,0 +1,193 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// Privacy statuses a video can have
+var privacyStatuses = []string{"public", "unlisted", "private"}
+
+// Options are the filter criteria given on the command line. Zero values
+// disable a criterion; all enabled criteria must match.
+type Options struct {
+	Since       string
+	Until       string
+	Match       string
+	Exclude     string
+	Privacy     []string
+	MinDuration string
+	MaxDuration string
+	Expr        string
+}
+
+// IsShort reports whether a video is likely a Short. The API has no Shorts
+// flag, so this goes by duration alone.
+func IsShort(video youtube.Video) bool {
+	return video.Duration > 0 && video.Duration <= constants.ShortsMaxDuration
+}
+
+// Build turns the options into a single video filter. It returns nil when no
+// criterion is set, so callers can skip filtering entirely.
+func Build(opts Options) (youtube.VideoFilter, error) {
+	return BuildAt(opts, time.Now())
+}
+
+// BuildAt is like Build but resolves relative dates (e.g. --since 7d) against now
+func BuildAt(opts Options, now time.Time) (youtube.VideoFilter, error) {
+	var filters []youtube.VideoFilter
+
+	if opts.Since != "" {
+		since, err := ParseTime(opts.Since, now)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(v youtube.Video) bool { return !v.PublishedAt.Before(since) })
+	}
+
+	if opts.Until != "" {
+		until, err := ParseTime(opts.Until, now)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(v youtube.Video) bool { return v.PublishedAt.Before(until) })
+	}
+
+	if opts.Match != "" {
+		re, err := compileRegexp("--match", opts.Match)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(v youtube.Video) bool { return re.MatchString(v.Title) })
+	}
+
+	if opts.Exclude != "" {
+		re, err := compileRegexp("--exclude", opts.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(v youtube.Video) bool { return !re.MatchString(v.Title) })
+	}
+
+	if len(opts.Privacy) > 0 {
+		allowed := make(map[string]bool, len(opts.Privacy))
+		for _, privacy := range opts.Privacy {
+			privacy = strings.ToLower(strings.TrimSpace(privacy))
+			if !isPrivacyStatus(privacy) {
+				return nil, errors.NewValidationError(fmt.Sprintf("invalid privacy status: %s", privacy)).
+					WithDetails(fmt.Sprintf("Valid privacy statuses: %s", strings.Join(privacyStatuses, ", ")))
+			}
+			allowed[privacy] = true
+		}
+		filters = append(filters, func(v youtube.Video) bool { return allowed[v.PrivacyStatus] })
+	}
+
+	if opts.MinDuration != "" {
+		min, err := parseDurationFlag("--min-duration", opts.MinDuration)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(v youtube.Video) bool { return v.Duration >= min })
+	}
+
+	if opts.MaxDuration != "" {
+		max, err := parseDurationFlag("--max-duration", opts.MaxDuration)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(v youtube.Video) bool { return v.Duration <= max })
+	}
+
+	if opts.Expr != "" {
+		expr, err := Compile(opts.Expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, expr)
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	return func(v youtube.Video) bool {
+		for _, f := range filters {
+			if !f(v) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// ParseDuration parses a Go duration (90s, 10m, 1h30m), additionally
+// accepting whole days and weeks (2d, 1w)
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// ParseTime parses an absolute date (2024-01-31 or RFC 3339) or a duration
+// relative to now (7d means seven days ago)
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if t, err := parseDate(s); err == nil {
+		return t, nil
+	}
+	if d, err := ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, errors.NewValidationError(fmt.Sprintf("invalid date: %s", s)).
+		WithDetails("Use a date (2024-01-31), a timestamp (2024-01-31T15:04:05Z) or a relative age (7d, 48h)")
+}
+
+// parseDate parses a date in local time, or an RFC 3339 timestamp
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// parseDurationFlag parses the duration given to a flag
+func parseDurationFlag(flag, s string) (time.Duration, error) {
+	d, err := ParseDuration(s)
+	if err != nil {
+		return 0, errors.NewValidationError(fmt.Sprintf("invalid %s: %s", flag, s)).
+			WithDetails("Use a duration such as 90s, 10m or 1h30m")
+	}
+	return d, nil
+}
+
+// compileRegexp compiles the regular expression given to a flag
+func compileRegexp(flag, pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid %s pattern: %v", flag, err))
+	}
+	return re, nil
+}
+
+// isPrivacyStatus reports whether s is a known privacy status
+func isPrivacyStatus(s string) bool {
+	for _, status := range privacyStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package format
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+// HDR preferences
+const (
+	HDRAny    = ""
+	HDRPrefer = "prefer"
+	HDRAvoid  = "avoid"
+)
+
+// codecPatterns match the yt-dlp vcodec values of the supported video codecs
+var codecPatterns = map[string]string{
+	"av1":  "^av0?1",
+	"vp9":  "^vp0?9",
+	"avc":  "^(avc|h264)",
+	"hevc": "^(hev|hvc|h265)",
+}
+
+// Supported containers and audio codecs
+var (
+	Containers  = []string{"mp4", "mkv", "webm"}
+	AudioCodecs = []string{"best", "mp3", "m4a", "aac", "opus", "vorbis", "flac", "wav"}
+)
+
+// namePattern restricts profile names, which end up in file names
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Profile describes which formats of a video are downloaded and how they are
+// stored. Profiles are configured by name under format_profiles in the
+// config file; the qualities best, 1080p, 720p and 480p are built in.
+type Profile struct {
+	// Name is the profile's key in the config; empty for --format
+	Name string `json:"-"`
+	// Format is a raw yt-dlp format selector, used instead of one built from
+	// MaxHeight, MaxFPS, Codecs and HDR
+	Format string `json:"format,omitempty"`
+	// MaxHeight caps the video resolution, e.g. 1080
+	MaxHeight int `json:"max_height,omitempty"`
+	// MaxFPS caps the frame rate, e.g. 30
+	MaxFPS int `json:"max_fps,omitempty"`
+	// Codecs lists the preferred video codecs in order: av1, vp9, avc, hevc
+	Codecs []string `json:"codecs,omitempty"`
+	// HDR is prefer, avoid or empty for no preference
+	HDR string `json:"hdr,omitempty"`
+	// Container merges or remuxes the video into mp4, mkv or webm
+	Container string `json:"container,omitempty"`
+	// AudioOnly extracts the audio track
+	AudioOnly bool `json:"audio_only,omitempty"`
+	// AudioCodec converts extracted audio, e.g. mp3 or opus
+	AudioCodec string `json:"audio_codec,omitempty"`
+	// AudioBitrate is the target bitrate of converted audio in kbit/s
+	AudioBitrate int `json:"audio_bitrate,omitempty"`
+
+	builtin bool
+}
+
+// builtinHeights are the resolutions of the built-in quality profiles
+var builtinHeights = map[string]int{
+	constants.QualityBest:  0,
+	constants.Quality1080p: 1080,
+	constants.Quality720p:  720,
+	constants.Quality480p:  480,
+}
+
+// Builtin returns the built-in profile of a quality name
+func Builtin(name string) (*Profile, bool) {
+	height, ok := builtinHeights[name]
+	if !ok {
+		return nil, false
+	}
+	return &Profile{Name: name, MaxHeight: height, builtin: true}, true
+}
+
+// IsBuiltin reports whether name is a built-in quality profile
+func IsBuiltin(name string) bool {
+	_, ok := builtinHeights[name]
+	return ok
+}
+
+// Raw returns a profile for a raw yt-dlp format selector
+func Raw(selector string) *Profile {
+	return &Profile{Format: selector}
+}
+
+// Lookup finds a profile by name among the built-in and configured ones
+func Lookup(name string, configured map[string]*Profile) (*Profile, error) {
+	if profile, ok := Builtin(name); ok {
+		return profile, nil
+	}
+
+	profile, ok := configured[name]
+	if !ok || profile == nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("unknown format profile: %s", name)).
+			WithDetails(fmt.Sprintf("Available profiles: %s", strings.Join(Names(configured), ", ")))
+	}
+
+	named := *profile
+	named.Name = name
+	if err := named.Validate(); err != nil {
+		return nil, err
+	}
+	return &named, nil
+}
+
+// Names lists the built-in profile names followed by the configured ones
+func Names(configured map[string]*Profile) []string {
+	names := append([]string{}, constants.SupportedQualities...)
+	var custom []string
+	for name := range configured {
+		if !IsBuiltin(name) {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+	return append(names, custom...)
+}
+
+// ValidateConfigured checks every configured profile, in name order
+func ValidateConfigured(configured map[string]*Profile) error {
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		profile := Profile{Name: name}
+		if configured[name] != nil {
+			profile = *configured[name]
+			profile.Name = name
+		}
+		if err := profile.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks a profile's settings
+func (p *Profile) Validate() error {
+	invalid := func(message string) error {
+		return errors.NewValidationError(fmt.Sprintf("format profile %s: %s", p.label(), message))
+	}
+
+	if p.Name != "" && !p.builtin {
+		if IsBuiltin(p.Name) {
+			return invalid("the name is reserved for a built-in quality")
+		}
+		if !namePattern.MatchString(p.Name) {
+			return invalid("names may only contain lowercase letters, digits, '-' and '_'")
+		}
+	}
+
+	if p.Format != "" && (p.MaxHeight != 0 || p.MaxFPS != 0 || len(p.Codecs) > 0 || p.HDR != HDRAny) {
+		return invalid("format cannot be combined with max_height, max_fps, codecs or hdr")
+	}
+	if p.MaxHeight < 0 || p.MaxFPS < 0 || p.AudioBitrate < 0 {
+		return invalid("max_height, max_fps and audio_bitrate cannot be negative")
+	}
+	for _, codec := range p.Codecs {
+		if _, ok := codecPatterns[codec]; !ok {
+			return invalid(fmt.Sprintf("unknown codec %q (use av1, vp9, avc or hevc)", codec))
+		}
+	}
+	switch p.HDR {
+	case HDRAny, HDRPrefer, HDRAvoid:
+	default:
+		return invalid(fmt.Sprintf("hdr must be %s or %s", HDRPrefer, HDRAvoid))
+	}
+	if p.Container != "" && !contains(Containers, p.Container) {
+		return invalid(fmt.Sprintf("container must be one of %s", strings.Join(Containers, ", ")))
+	}
+
+	if p.AudioOnly {
+		if p.Container != "" || p.MaxHeight != 0 || p.MaxFPS != 0 || len(p.Codecs) > 0 || p.HDR != HDRAny {
+			return invalid("audio-only profiles take audio_codec and audio_bitrate, not video settings")
+		}
+	} else if p.AudioCodec != "" || p.AudioBitrate != 0 {
+		return invalid("audio_codec and audio_bitrate need audio_only")
+	}
+	if p.AudioCodec != "" && !contains(AudioCodecs, p.AudioCodec) {
+		return invalid(fmt.Sprintf("audio_codec must be one of %s", strings.Join(AudioCodecs, ", ")))
+	}
+	return nil
+}
+
+// Primary reports whether the profile stores files under the video's plain
+// output path. Built-in qualities and --format share that slot; configured
+// profiles add their name, e.g. "Title [ID] (podcast).mp3".
+func (p *Profile) Primary() bool {
+	return p.builtin || p.Name == ""
+}
+
+// Key identifies the profile in the download archive: empty for the primary
+// slot, the profile name otherwise
+func (p *Profile) Key() string {
+	if p.Primary() {
+		return ""
+	}
+	return p.Name
+}
+
+// Suffix is appended to the video's output path for the profile's files
+func (p *Profile) Suffix() string {
+	if p.Primary() {
+		return ""
+	}
+	return " (" + p.Name + ")"
+}
+
+// String describes the profile for logs and the archive
+func (p *Profile) String() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Format
+}
+
+// label names the profile in error messages
+func (p *Profile) label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return "--format"
+}
+
+// Args returns the yt-dlp arguments selecting and converting formats
+func (p *Profile) Args() []string {
+	args := []string{"-f", p.Selector()}
+
+	if p.AudioOnly {
+		if p.AudioCodec != "" || p.AudioBitrate != 0 {
+			args = append(args, "--extract-audio")
+		}
+		if p.AudioCodec != "" {
+			args = append(args, "--audio-format", p.AudioCodec)
+		}
+		if p.AudioBitrate != 0 {
+			args = append(args, "--audio-quality", strconv.Itoa(p.AudioBitrate)+"K")
+		}
+	}
+
+	if p.Container != "" {
+		args = append(args, "--merge-output-format", p.Container, "--remux-video", p.Container)
+	}
+	return args
+}
+
+// Selector returns the yt-dlp format selector. Each preferred codec and HDR
+// choice is tried in turn before falling back to the best format within the
+// resolution and frame rate caps.
+func (p *Profile) Selector() string {
+	if p.Format != "" {
+		return p.Format
+	}
+	if p.AudioOnly {
+		return "bestaudio/best"
+	}
+
+	limits := ""
+	if p.MaxHeight > 0 {
+		limits += fmt.Sprintf("[height<=%d]", p.MaxHeight)
+	}
+	if p.MaxFPS > 0 {
+		limits += fmt.Sprintf("[fps<=%d]", p.MaxFPS)
+	}
+
+	hdrFilters := []string{""}
+	switch p.HDR {
+	case HDRPrefer:
+		hdrFilters = []string{"[dynamic_range!=SDR]", ""}
+	case HDRAvoid:
+		hdrFilters = []string{"[dynamic_range=SDR]", ""}
+	}
+
+	codecFilters := []string{}
+	for _, codec := range p.Codecs {
+		codecFilters = append(codecFilters, fmt.Sprintf("[vcodec~='%s']", codecPatterns[codec]))
+	}
+	codecFilters = append(codecFilters, "")
+
+	var alternatives []string
+	for _, hdr := range hdrFilters {
+		for _, codec := range codecFilters {
+			alternatives = append(alternatives, "bestvideo"+limits+codec+hdr+"+bestaudio")
+		}
+	}
+	alternatives = append(alternatives, "best"+limits)
+	return strings.Join(alternatives, "/")
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
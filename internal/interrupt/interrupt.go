@@ -0,0 +1,75 @@
+package interrupt
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+)
+
+// ExitCode is the conventional exit status after an interrupt
+const ExitCode = 130
+
+// abortKey is the context key of the abort context
+type abortKey struct{}
+
+// WithSignals returns a context that is cancelled by the first SIGINT or
+// SIGTERM, meaning no new work should start. The second signal cancels the
+// context returned by Abort, meaning work in flight should stop now; a third
+// exits immediately. stop releases the signal handler.
+func WithSignals(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel, abort := WithAbort(parent)
+
+	signals := make(chan os.Signal, 3)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		for count := 1; ; count++ {
+			select {
+			case <-signals:
+			case <-done:
+				return
+			}
+
+			switch count {
+			case 1:
+				logger.Warn("Interrupted: letting downloads in progress finish, starting no new ones (interrupt again to stop them)")
+				cancel()
+			case 2:
+				logger.Warn("Interrupted again: stopping downloads in progress")
+				abort()
+			default:
+				os.Exit(ExitCode)
+			}
+		}
+	}()
+
+	stop = func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+		abort()
+	}
+	return ctx, stop
+}
+
+// WithAbort returns a context that cancel cancels, meaning no new work should
+// start, and whose Abort context abort cancels, meaning work in flight should
+// stop now. WithSignals drives both from signals.
+func WithAbort(parent context.Context) (ctx context.Context, cancel, abort context.CancelFunc) {
+	abortCtx, abort := context.WithCancel(parent)
+	ctx, cancel = context.WithCancel(context.WithValue(parent, abortKey{}, abortCtx))
+	return ctx, cancel, abort
+}
+
+// Abort returns the context that is cancelled when work in flight must stop
+// immediately. For contexts not derived from WithSignals it is ctx itself.
+func Abort(ctx context.Context) context.Context {
+	if abortCtx, ok := ctx.Value(abortKey{}).(context.Context); ok {
+		return abortCtx
+	}
+	return ctx
+}
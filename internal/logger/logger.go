@@ -129,6 +129,13 @@ func (l *Logger) SetVerbose(verbose bool) {
 	l.verbose = verbose
 }
 
+// SetOutput redirects log output, returning the previous writer
+func (l *Logger) SetOutput(w io.Writer) io.Writer {
+	previous := l.logger.Writer()
+	l.logger.SetOutput(w)
+	return previous
+}
+
 // log formats and logs a message
 func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 	if level < l.level {
@@ -201,6 +208,11 @@ func Fatal(format string, args ...interface{}) {
 	GetLogger().Fatal(format, args...)
 }
 
+// SetOutput redirects the default logger's output, returning the previous writer
+func SetOutput(w io.Writer) io.Writer {
+	return GetLogger().SetOutput(w)
+}
+
 // LogError logs an error with additional context
 func LogError(err error, context string) {
 	if err != nil {
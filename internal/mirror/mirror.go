@@ -0,0 +1,333 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// Prune modes for videos removed from the channel
+const (
+	PruneNone   = "none"
+	PruneMove   = "move"
+	PruneDelete = "delete"
+)
+
+// infoSuffix is the suffix of the metadata files yt-dlp writes
+const infoSuffix = ".info.json"
+
+// videoFileSuffix matches what follows "<name>." in the files written for a
+// video: media and thumbnails, yt-dlp's per-format and partial files, the
+// metadata files and captions. Other videos' files sharing the directory,
+// e.g. "<name>.2 (other).mp4", do not match.
+var videoFileSuffix = regexp.MustCompile(`^(?:` +
+	`(?:f[\w-]+\.)?(?:temp\.)?[A-Za-z0-9]{1,5}(?:\.part(?:-Frag\d+)?(?:\.part)?|\.ytdl)?` +
+	`|info\.json|description` +
+	`|[A-Za-z]{2,3}(?:-[A-Za-z0-9]+)*(?:\.[^.]+)?\.(?:srt|vtt)` +
+	`)$`)
+
+// LocalVideo is a video found in the output directory through its info.json
+type LocalVideo struct {
+	ID           string
+	Title        string
+	Availability string
+	InfoPath     string
+}
+
+// BasePath returns the video's output path without extension
+func (v LocalVideo) BasePath() string {
+	return strings.TrimSuffix(v.InfoPath, infoSuffix)
+}
+
+// Files returns all files written for the video
+func (v LocalVideo) Files() ([]string, error) {
+	dir := filepath.Dir(v.InfoPath)
+	prefix := filepath.Base(v.BasePath()) + "."
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to read video directory")
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, prefix) && videoFileSuffix.MatchString(name[len(prefix):]) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// infoJSON holds the info.json fields sync needs
+type infoJSON struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Availability string `json:"availability"`
+	Type         string `json:"_type"`
+}
+
+// ValidatePruneMode checks a --prune value
+func ValidatePruneMode(mode string) error {
+	switch mode {
+	case PruneNone, PruneMove, PruneDelete:
+		return nil
+	default:
+		return errors.NewValidationError(fmt.Sprintf("invalid prune mode: %s", mode)).
+			WithDetails(fmt.Sprintf("Valid prune modes: %s, %s, %s", PruneNone, PruneMove, PruneDelete))
+	}
+}
+
+// Scan finds the videos already in the output directory by reading the
+// info.json files written next to each download. The state directory and the
+// removed-videos folder are skipped.
+func Scan(outputDir string) (map[string]LocalVideo, error) {
+	videos := make(map[string]LocalVideo)
+
+	err := filepath.WalkDir(outputDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == outputDir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != outputDir && (entry.Name() == constants.StateDirName || entry.Name() == constants.RemovedDirName) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(entry.Name(), infoSuffix) {
+			return nil
+		}
+
+		video, ok := readInfo(path)
+		if !ok {
+			return nil
+		}
+		if existing, dup := videos[video.ID]; dup {
+			logger.Warn("Video %s appears twice: %s and %s", video.ID, existing.InfoPath, path)
+			return nil
+		}
+		videos[video.ID] = video
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to scan output directory")
+	}
+
+	logger.Debug("Found %d downloaded videos in %s", len(videos), outputDir)
+	return videos, nil
+}
+
+// readInfo parses an info.json file, ignoring anything that is not a video
+func readInfo(path string) (LocalVideo, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Warn("Unable to read %s: %v", path, err)
+		return LocalVideo{}, false
+	}
+
+	var info infoJSON
+	if err := json.Unmarshal(data, &info); err != nil {
+		logger.Warn("Unable to parse %s: %v", path, err)
+		return LocalVideo{}, false
+	}
+
+	if (info.Type != "" && info.Type != "video") || validation.ValidateVideoID(info.ID) != nil {
+		return LocalVideo{}, false
+	}
+
+	return LocalVideo{ID: info.ID, Title: info.Title, Availability: info.Availability, InfoPath: path}, true
+}
+
+// Lookup fetches videos by ID, omitting those that no longer exist or are not
+// accessible
+type Lookup func(ids []string) ([]youtube.Video, error)
+
+// Result is the outcome of comparing the channel with the output directory
+type Result struct {
+	// New are channel videos not yet downloaded, in listing order
+	New []youtube.Video
+	// Removed are downloaded videos no longer on the channel
+	Removed []LocalVideo
+	// Events are the changes newly detected by this sync
+	Events []Event
+}
+
+// Diff compares the channel listing with the downloaded videos, updating the
+// state and recording removed, privated and retitled videos. Downloaded
+// videos missing from the listing are looked up individually, since e.g.
+// private videos of another account do not appear in channel listings.
+func (s *State) Diff(channelID string, local map[string]LocalVideo, remote []youtube.Video, lookup Lookup, now time.Time) (*Result, error) {
+	result := &Result{}
+	s.ChannelID = channelID
+
+	known := make(map[string]youtube.Video, len(remote))
+	for _, video := range remote {
+		known[video.ID] = video
+		if _, ok := local[video.ID]; !ok {
+			result.New = append(result.New, video)
+		}
+	}
+
+	var missing []string
+	for id := range local {
+		if _, ok := known[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+
+	if len(missing) > 0 {
+		found, err := lookup(missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, video := range found {
+			known[video.ID] = video
+		}
+	}
+
+	ids := make([]string, 0, len(local))
+	for id := range local {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		localVideo := local[id]
+		state := s.videoState(localVideo, now)
+		video, ok := known[id]
+
+		if !ok {
+			result.Removed = append(result.Removed, localVideo)
+			if state.Status != StatusRemoved {
+				state.Status = StatusRemoved
+				result.Events = append(result.Events, s.record(now, EventRemoved, id, state.Title, "", ""))
+			}
+			continue
+		}
+
+		state.LastSeen = now
+
+		if video.Title != "" && video.Title != state.Title {
+			result.Events = append(result.Events, s.record(now, EventTitleChanged, id, video.Title, state.Title, video.Title))
+			state.Title = video.Title
+		}
+
+		if video.PrivacyStatus == "private" && state.PrivacyStatus != "private" {
+			result.Events = append(result.Events, s.record(now, EventPrivated, id, state.Title, state.PrivacyStatus, video.PrivacyStatus))
+		}
+		if video.PrivacyStatus != "" {
+			state.PrivacyStatus = video.PrivacyStatus
+		}
+
+		state.Status = StatusPresent
+		if state.PrivacyStatus == "private" {
+			state.Status = StatusPrivate
+		}
+	}
+
+	return result, nil
+}
+
+// MarkAdded records videos that were downloaded by this sync
+func (s *State) MarkAdded(videos []youtube.Video, now time.Time) []Event {
+	var events []Event
+	for _, video := range videos {
+		status := StatusPresent
+		if video.PrivacyStatus == "private" {
+			status = StatusPrivate
+		}
+		s.Videos[video.ID] = &VideoState{
+			Title:         video.Title,
+			PrivacyStatus: video.PrivacyStatus,
+			Status:        status,
+			FirstSeen:     now,
+			LastSeen:      now,
+		}
+		events = append(events, s.record(now, EventAdded, video.ID, video.Title, "", ""))
+	}
+	return events
+}
+
+// Finish stamps the time of the completed sync
+func (s *State) Finish(now time.Time) {
+	s.LastSync = now
+}
+
+// videoState returns the state of a downloaded video, seeding it from its
+// info.json the first time it is seen
+func (s *State) videoState(video LocalVideo, now time.Time) *VideoState {
+	state, ok := s.Videos[video.ID]
+	if !ok {
+		state = &VideoState{
+			Title:         video.Title,
+			PrivacyStatus: video.Availability,
+			Status:        StatusPresent,
+			FirstSeen:     now,
+			LastSeen:      now,
+		}
+		s.Videos[video.ID] = state
+	}
+	return state
+}
+
+// Prune handles the files of videos removed from the channel: PruneMove
+// relocates them into <output>/_removed/ keeping their relative paths,
+// PruneDelete deletes them and PruneNone leaves them in place. It returns the
+// number of files affected.
+func Prune(outputDir string, videos []LocalVideo, mode string) (int, error) {
+	if mode == PruneNone || len(videos) == 0 {
+		return 0, nil
+	}
+
+	count := 0
+	for _, video := range videos {
+		files, err := video.Files()
+		if err != nil {
+			return count, err
+		}
+
+		for _, file := range files {
+			switch mode {
+			case PruneMove:
+				rel, err := filepath.Rel(outputDir, file)
+				if err != nil {
+					return count, errors.WrapFile(err, "unable to locate removed file")
+				}
+				target := filepath.Join(outputDir, constants.RemovedDirName, rel)
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return count, errors.WrapFile(err, "unable to create removed-videos folder")
+				}
+				if err := os.Rename(file, target); err != nil {
+					return count, errors.WrapFile(err, fmt.Sprintf("unable to move %s", file))
+				}
+				logger.Info("Moved %s to %s", file, target)
+			case PruneDelete:
+				if err := os.Remove(file); err != nil {
+					return count, errors.WrapFile(err, fmt.Sprintf("unable to delete %s", file))
+				}
+				logger.Info("Deleted %s", file)
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,288 @@
+package mirror
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/youtube"
+)
+
+// lookupOf returns a Lookup finding the given videos and recording the IDs
+// it was asked for
+func lookupOf(asked *[]string, videos ...youtube.Video) Lookup {
+	byID := make(map[string]youtube.Video, len(videos))
+	for _, video := range videos {
+		byID[video.ID] = video
+	}
+	return func(ids []string) ([]youtube.Video, error) {
+		*asked = append(*asked, ids...)
+		var found []youtube.Video
+		for _, id := range ids {
+			if video, ok := byID[id]; ok {
+				found = append(found, video)
+			}
+		}
+		return found, nil
+	}
+}
+
+// eventKinds returns "kind:id" for each event
+func eventKinds(events []Event) []string {
+	var kinds []string
+	for _, event := range events {
+		kinds = append(kinds, event.Kind+":"+event.VideoID)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func TestDiff(t *testing.T) {
+	local := map[string]LocalVideo{
+		"aaaaaaaaaaa": {ID: "aaaaaaaaaaa", Title: "Kept"},
+		"bbbbbbbbbbb": {ID: "bbbbbbbbbbb", Title: "Privated", Availability: "public"},
+		"ccccccccccc": {ID: "ccccccccccc", Title: "Deleted"},
+		"ddddddddddd": {ID: "ddddddddddd", Title: "Old title"},
+	}
+	remote := []youtube.Video{
+		{ID: "aaaaaaaaaaa", Title: "Kept", PrivacyStatus: "public"},
+		{ID: "ddddddddddd", Title: "New title", PrivacyStatus: "public"},
+		{ID: "eeeeeeeeeee", Title: "Brand new", PrivacyStatus: "public"},
+	}
+	// Private videos drop out of the listing but are still found by ID
+	var asked []string
+	lookup := lookupOf(&asked, youtube.Video{ID: "bbbbbbbbbbb", Title: "Privated", PrivacyStatus: "private"})
+
+	state, err := LoadState(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	result, err := state.Diff("UCchannel", local, remote, lookup, first)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	if want := []string{"bbbbbbbbbbb", "ccccccccccc"}; !reflect.DeepEqual(asked, want) {
+		t.Errorf("looked up %v, want %v", asked, want)
+	}
+	if len(result.New) != 1 || result.New[0].ID != "eeeeeeeeeee" {
+		t.Errorf("New = %v, want eeeeeeeeeee", result.New)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].ID != "ccccccccccc" {
+		t.Errorf("Removed = %v, want ccccccccccc", result.Removed)
+	}
+	want := []string{"privated:bbbbbbbbbbb", "removed:ccccccccccc", "title_changed:ddddddddddd"}
+	if got := eventKinds(result.Events); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	for id, status := range map[string]string{
+		"aaaaaaaaaaa": StatusPresent,
+		"bbbbbbbbbbb": StatusPrivate,
+		"ccccccccccc": StatusRemoved,
+		"ddddddddddd": StatusPresent,
+	} {
+		if got := state.Videos[id].Status; got != status {
+			t.Errorf("status of %s = %q, want %q", id, got, status)
+		}
+	}
+	if title := state.Videos["ddddddddddd"].Title; title != "New title" {
+		t.Errorf("title of ddddddddddd = %q, want the new title", title)
+	}
+	if state.ChannelID != "UCchannel" {
+		t.Errorf("ChannelID = %q, want UCchannel", state.ChannelID)
+	}
+
+	// Changes are reported once
+	asked = nil
+	result, err = state.Diff("UCchannel", local, remote, lookup, first.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("second Diff() error = %v", err)
+	}
+	if len(result.Events) != 0 {
+		t.Errorf("second Diff() events = %v, want none", eventKinds(result.Events))
+	}
+	if len(result.Removed) != 1 {
+		t.Errorf("second Diff() removed = %v, want the deleted video again", result.Removed)
+	}
+	if !state.Videos["aaaaaaaaaaa"].LastSeen.Equal(first.Add(time.Hour)) {
+		t.Errorf("LastSeen = %v, want the second sync", state.Videos["aaaaaaaaaaa"].LastSeen)
+	}
+	if !state.Videos["aaaaaaaaaaa"].FirstSeen.Equal(first) {
+		t.Errorf("FirstSeen = %v, want the first sync", state.Videos["aaaaaaaaaaa"].FirstSeen)
+	}
+}
+
+func TestDiffLookupError(t *testing.T) {
+	state, err := LoadState(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := map[string]LocalVideo{"aaaaaaaaaaa": {ID: "aaaaaaaaaaa"}}
+	lookup := func(ids []string) ([]youtube.Video, error) {
+		return nil, fmt.Errorf("quota exceeded")
+	}
+
+	if _, err := state.Diff("UCchannel", local, nil, lookup, time.Now()); err == nil {
+		t.Fatal("Diff() succeeded although the lookup failed")
+	}
+	if state.Videos["aaaaaaaaaaa"] != nil && state.Videos["aaaaaaaaaaa"].Status == StatusRemoved {
+		t.Error("video marked removed although the lookup failed")
+	}
+}
+
+func TestMarkAdded(t *testing.T) {
+	state, err := LoadState(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	events := state.MarkAdded([]youtube.Video{
+		{ID: "aaaaaaaaaaa", Title: "Public", PrivacyStatus: "public"},
+		{ID: "bbbbbbbbbbb", Title: "Mine", PrivacyStatus: "private"},
+	}, now)
+
+	if want := []string{"added:aaaaaaaaaaa", "added:bbbbbbbbbbb"}; !reflect.DeepEqual(eventKinds(events), want) {
+		t.Errorf("events = %v, want %v", eventKinds(events), want)
+	}
+	if len(state.Events) != 2 {
+		t.Errorf("state records %d events, want 2", len(state.Events))
+	}
+	if got := state.Videos["aaaaaaaaaaa"]; got.Status != StatusPresent || !got.FirstSeen.Equal(now) || got.Title != "Public" {
+		t.Errorf("state of aaaaaaaaaaa = %+v", got)
+	}
+	if got := state.Videos["bbbbbbbbbbb"].Status; got != StatusPrivate {
+		t.Errorf("status of bbbbbbbbbbb = %q, want %q", got, StatusPrivate)
+	}
+}
+
+// writeFiles creates empty files below dir
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// existing returns which of the names exist below dir
+func existing(dir string, names ...string) []string {
+	var found []string
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+func TestPrune(t *testing.T) {
+	removed := []string{
+		"Foo.mp4",
+		"Foo.info.json",
+		"Foo.description",
+		"Foo.webp",
+		"Foo.en.srt",
+		"Foo.de.Director's cut.srt",
+		"Foo.f137.mp4.part",
+		"Foo.f251.webm.part-Frag3",
+		"Foo.temp.mkv",
+		"Foo.mp4.ytdl",
+	}
+	// Files of other videos in the same directory
+	kept := []string{
+		"Foo.bar (xyz).mp4",
+		"Foo.bar (xyz).info.json",
+		"Foo 2.mp4",
+		"Foo (podcast).mp3",
+		"Foobar.mp4",
+	}
+
+	tests := []struct {
+		mode      string
+		wantCount int
+		moved     bool
+	}{
+		{mode: PruneNone, wantCount: 0},
+		{mode: PruneMove, wantCount: len(removed), moved: true},
+		{mode: PruneDelete, wantCount: len(removed)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			outputDir := t.TempDir()
+			dir := filepath.Join(outputDir, "2024")
+			writeFiles(t, dir, append(append([]string{}, removed...), kept...)...)
+			video := LocalVideo{ID: "aaaaaaaaaaa", Title: "Foo", InfoPath: filepath.Join(dir, "Foo.info.json")}
+
+			count, err := Prune(outputDir, []LocalVideo{video}, tt.mode)
+			if err != nil {
+				t.Fatalf("Prune() error = %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("Prune() = %d, want %d", count, tt.wantCount)
+			}
+
+			if got := existing(dir, kept...); !reflect.DeepEqual(got, kept) {
+				t.Errorf("other videos' files left = %v, want %v", got, kept)
+			}
+
+			left := existing(dir, removed...)
+			if tt.mode == PruneNone {
+				if !reflect.DeepEqual(left, removed) {
+					t.Errorf("files left = %v, want all of them", left)
+				}
+				return
+			}
+			if len(left) != 0 {
+				t.Errorf("files left = %v, want none", left)
+			}
+
+			movedDir := filepath.Join(outputDir, constants.RemovedDirName, "2024")
+			moved := existing(movedDir, removed...)
+			if tt.moved && !reflect.DeepEqual(moved, removed) {
+				t.Errorf("files moved = %v, want %v", moved, removed)
+			}
+			if !tt.moved && len(moved) != 0 {
+				t.Errorf("files moved = %v, want none", moved)
+			}
+		})
+	}
+}
+
+func TestPruneScannedVideo(t *testing.T) {
+	outputDir := t.TempDir()
+	writeFiles(t, outputDir, "Foo.mp4", "Foo.bar (xyz).mp4")
+	if err := os.WriteFile(filepath.Join(outputDir, "Foo.info.json"), []byte(`{"id":"aaaaaaaaaaa","title":"Foo","_type":"video"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := Scan(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Prune(outputDir, []LocalVideo{local["aaaaaaaaaaa"]}, PruneMove); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	// Pruned files are not scanned again
+	if local, err = Scan(outputDir); err != nil || len(local) != 0 {
+		t.Errorf("Scan() after pruning = %v, %v; want nothing", local, err)
+	}
+	if got := existing(outputDir, "Foo.mp4", "Foo.bar (xyz).mp4"); !reflect.DeepEqual(got, []string{"Foo.bar (xyz).mp4"}) {
+		t.Errorf("files left = %v, want only the other video's", got)
+	}
+}
@@ -0,0 +1,115 @@
+package mirror
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/fsutil"
+)
+
+// Video statuses tracked in the sync state
+const (
+	StatusPresent = "present"
+	StatusPrivate = "private"
+	StatusRemoved = "removed"
+)
+
+// Event kinds recorded in the sync state
+const (
+	EventAdded        = "added"
+	EventRemoved      = "removed"
+	EventPrivated     = "privated"
+	EventTitleChanged = "title_changed"
+)
+
+// VideoState is what the last sync knew about a video
+type VideoState struct {
+	Title         string    `json:"title"`
+	PrivacyStatus string    `json:"privacy_status,omitempty"`
+	Status        string    `json:"status"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+// Event is a change detected by a sync
+type Event struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	VideoID string    `json:"video_id"`
+	Title   string    `json:"title"`
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to,omitempty"`
+}
+
+// State is the sync state of an output directory, kept at
+// <output>/.yeetrap/sync.json
+type State struct {
+	Version   int                    `json:"version"`
+	ChannelID string                 `json:"channel_id"`
+	LastSync  time.Time              `json:"last_sync"`
+	Videos    map[string]*VideoState `json:"videos"`
+	Events    []Event                `json:"events"`
+
+	path string
+}
+
+// StatePath returns the sync state location for an output directory
+func StatePath(outputDir string) string {
+	return filepath.Join(outputDir, constants.StateDirName, constants.SyncStateFile)
+}
+
+// LoadState reads the sync state of an output directory, returning an empty
+// state if none exists yet
+func LoadState(outputDir string) (*State, error) {
+	state := &State{Version: 1, Videos: make(map[string]*VideoState), path: StatePath(outputDir)}
+
+	data, err := os.ReadFile(state.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to read sync state")
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.WrapFile(err, "unable to parse sync state").
+			WithContext("path", state.path)
+	}
+	if state.Videos == nil {
+		state.Videos = make(map[string]*VideoState)
+	}
+	return state, nil
+}
+
+// Save writes the sync state
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.WrapFile(err, "unable to encode sync state")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return errors.WrapFile(err, "unable to create sync state directory")
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data, 0644); err != nil {
+		return errors.WrapFile(err, "unable to write sync state")
+	}
+	return nil
+}
+
+// Location returns the path of the sync state file
+func (s *State) Location() string {
+	return s.path
+}
+
+// record appends an event
+func (s *State) record(now time.Time, kind, videoID, title, from, to string) Event {
+	event := Event{Time: now, Kind: kind, VideoID: videoID, Title: title, From: from, To: to}
+	s.Events = append(s.Events, event)
+	return event
+}
@@ -0,0 +1,318 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/fsutil"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+)
+
+// Info describes an account profile
+type Info struct {
+	Name           string
+	Dir            string
+	Default        bool
+	HasCredentials bool
+	HasToken       bool
+}
+
+// registry is the on-disk list of profile settings
+type registry struct {
+	DefaultProfile string `json:"default_profile"`
+}
+
+// legacyFiles are the files a single-account install kept directly in the config dir
+var legacyFiles = []string{constants.CredentialsFile, constants.TokenFile, constants.ConfigFile}
+
+// Dir returns the directory of the named profile
+func Dir(name string) (string, error) {
+	profilesDir, err := constants.GetProfilesDir()
+	if err != nil {
+		return "", errors.WrapConfig(err, "failed to get profiles directory")
+	}
+	return filepath.Join(profilesDir, name), nil
+}
+
+// Exists reports whether the named profile has been created
+func Exists(name string) bool {
+	dir, err := Dir(name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// List returns all profiles sorted by name
+func List() ([]Info, error) {
+	profilesDir, err := constants.GetProfilesDir()
+	if err != nil {
+		return nil, errors.WrapConfig(err, "failed to get profiles directory")
+	}
+
+	defaultName, err := Default()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(profilesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.WrapFile(err, "unable to read profiles directory")
+	}
+
+	var profiles []Info
+	for _, entry := range entries {
+		if !entry.IsDir() || validation.ValidateProfileName(entry.Name()) != nil {
+			continue
+		}
+
+		dir := filepath.Join(profilesDir, entry.Name())
+		profiles = append(profiles, Info{
+			Name:           entry.Name(),
+			Dir:            dir,
+			Default:        entry.Name() == defaultName,
+			HasCredentials: fileExists(filepath.Join(dir, constants.CredentialsFile)),
+			HasToken: fileExists(filepath.Join(dir, constants.TokenFile)) ||
+				fileExists(filepath.Join(dir, constants.EncryptedTokenFile)),
+		})
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// Add creates a new profile. The OAuth2 client credentials of the default
+// profile are copied over, since one client can authorize several accounts.
+func Add(name string) (*Info, error) {
+	if err := validation.ValidateProfileName(name); err != nil {
+		return nil, err
+	}
+
+	if Exists(name) {
+		return nil, errors.NewConfigError(fmt.Sprintf("profile already exists: %s", name))
+	}
+
+	dir, err := Dir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.WrapFile(err, "unable to create profile directory")
+	}
+
+	info := &Info{Name: name, Dir: dir}
+
+	defaultName, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	defaultDir, err := Dir(defaultName)
+	if err != nil {
+		return nil, err
+	}
+
+	src := filepath.Join(defaultDir, constants.CredentialsFile)
+	if data, err := os.ReadFile(src); err == nil {
+		if err := fsutil.WriteFileAtomic(filepath.Join(dir, constants.CredentialsFile), data, 0600); err != nil {
+			return nil, errors.WrapFile(err, "unable to copy credentials into profile")
+		}
+		info.HasCredentials = true
+		logger.Info("Copied credentials from profile %s into %s", defaultName, name)
+	}
+
+	logger.Info("Created profile %s", name)
+	return info, nil
+}
+
+// Remove deletes a profile and everything stored in it
+func Remove(name string) error {
+	if err := validation.ValidateProfileName(name); err != nil {
+		return err
+	}
+
+	if !Exists(name) {
+		return errors.NewConfigError(fmt.Sprintf("profile not found: %s", name))
+	}
+
+	defaultName, err := Default()
+	if err != nil {
+		return err
+	}
+	if name == defaultName {
+		return errors.NewConfigError(fmt.Sprintf("cannot remove the default profile: %s", name)).
+			WithDetails("Choose another default first with 'yeetrap profile default <name>'")
+	}
+
+	dir, err := Dir(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.WrapFile(err, "unable to remove profile directory")
+	}
+
+	logger.Info("Removed profile %s", name)
+	return nil
+}
+
+// Default returns the name of the default profile
+func Default() (string, error) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return "", err
+	}
+
+	if reg.DefaultProfile == "" {
+		return constants.DefaultProfileName, nil
+	}
+	return reg.DefaultProfile, nil
+}
+
+// SetDefault makes the named profile the default one
+func SetDefault(name string) error {
+	if err := validation.ValidateProfileName(name); err != nil {
+		return err
+	}
+
+	if !Exists(name) {
+		return errors.NewConfigError(fmt.Sprintf("profile not found: %s", name)).
+			WithDetails(fmt.Sprintf("Create it first with 'yeetrap profile add %s'", name))
+	}
+
+	reg, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+
+	reg.DefaultProfile = name
+	return saveRegistry(reg)
+}
+
+// Resolve picks the profile to use: an explicit name wins, then the
+// YEETRAP_PROFILE environment variable, then the configured default
+func Resolve(name string) (string, error) {
+	if name == "" {
+		name = os.Getenv(constants.ProfileEnvVar)
+	}
+
+	if name == "" {
+		var err error
+		if name, err = Default(); err != nil {
+			return "", err
+		}
+	}
+
+	if err := validation.ValidateProfileName(name); err != nil {
+		return "", err
+	}
+
+	// The default profile is created on demand; others must be added explicitly
+	if name != constants.DefaultProfileName && !Exists(name) {
+		return "", errors.NewConfigError(fmt.Sprintf("profile not found: %s", name)).
+			WithDetails(fmt.Sprintf("Create it with 'yeetrap profile add %s'", name))
+	}
+
+	return name, nil
+}
+
+// Migrate moves the files of a pre-profile, single-account install into the
+// default profile. It is a no-op once migrated.
+func Migrate() error {
+	configDir, err := constants.GetConfigDir()
+	if err != nil {
+		return errors.WrapConfig(err, "failed to get config directory")
+	}
+
+	defaultDir, err := Dir(constants.DefaultProfileName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range legacyFiles {
+		legacyPath := filepath.Join(configDir, name)
+		if !fileExists(legacyPath) {
+			continue
+		}
+
+		if err := os.MkdirAll(defaultDir, 0700); err != nil {
+			return errors.WrapFile(err, "unable to create default profile directory")
+		}
+
+		target := filepath.Join(defaultDir, name)
+		if fileExists(target) {
+			logger.Warn("Not migrating %s: %s already exists", legacyPath, target)
+			continue
+		}
+
+		if err := os.Rename(legacyPath, target); err != nil {
+			return errors.WrapFile(err, fmt.Sprintf("unable to migrate %s into the default profile", name))
+		}
+		logger.Info("Migrated %s into profile %s", name, constants.DefaultProfileName)
+	}
+
+	return nil
+}
+
+// loadRegistry reads the profile registry, returning an empty one if absent
+func loadRegistry() (*registry, error) {
+	path, err := registryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &registry{}, nil
+	}
+	if err != nil {
+		return nil, errors.WrapFile(err, "unable to read profile registry")
+	}
+
+	reg := &registry{}
+	if err := json.Unmarshal(data, reg); err != nil {
+		return nil, errors.WrapConfig(err, "unable to parse profile registry")
+	}
+	return reg, nil
+}
+
+// saveRegistry writes the profile registry
+func saveRegistry(reg *registry) error {
+	path, err := registryPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return errors.WrapConfig(err, "unable to encode profile registry")
+	}
+
+	if err := fsutil.WriteFileAtomic(path, data, 0600); err != nil {
+		return errors.WrapFile(err, "unable to write profile registry")
+	}
+	return nil
+}
+
+// registryPath returns the path of the profile registry file
+func registryPath() (string, error) {
+	configDir, err := constants.GetConfigDir()
+	if err != nil {
+		return "", errors.WrapConfig(err, "failed to get config directory")
+	}
+	return filepath.Join(configDir, constants.ProfilesFile), nil
+}
+
+// fileExists reports whether path exists and is a regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
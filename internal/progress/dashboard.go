@@ -0,0 +1,230 @@
+package progress
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+const (
+	// dashboardInterval limits how often the live lines are redrawn, unless
+	// items start or finish
+	dashboardInterval = 100 * time.Millisecond
+	// defaultWidth is used when the terminal width is unknown
+	defaultWidth = 80
+	// barWidth is the width of the progress bars on wide terminals
+	barWidth = 20
+)
+
+// Dashboard renders one live line per item in flight and an overall line at
+// the bottom of the terminal. Finished items are printed above the live lines
+// and scroll away with the rest of the output.
+type Dashboard struct {
+	out      *os.File
+	last     *Progress
+	lines    int
+	finished int
+	items    int
+	drawnAt  time.Time
+	done     bool
+	mu       sync.Mutex
+}
+
+// NewDashboard creates a dashboard drawing on the terminal out
+func NewDashboard(out *os.File) *Dashboard {
+	fmt.Fprint(out, "\x1b[?25l") // hide the cursor while redrawing
+	return &Dashboard{out: out}
+}
+
+// Update redraws the dashboard, at most every dashboardInterval unless items
+// started or finished
+func (d *Dashboard) Update(p *Progress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done {
+		return
+	}
+	changed := len(p.Finished) != d.finished || len(p.Items) != d.items
+	if !changed && time.Since(d.drawnAt) < dashboardInterval {
+		d.last = p
+		return
+	}
+	d.drawLocked(p)
+}
+
+// Write prints log output above the live lines
+func (d *Dashboard) Write(b []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done {
+		return d.out.Write(b)
+	}
+
+	d.clearLocked()
+	n, err := d.out.Write(b)
+	if d.last != nil {
+		d.drawLocked(d.last)
+	}
+	return n, err
+}
+
+// Finish replaces the live lines with the final summary and restores the cursor
+func (d *Dashboard) Finish(p *Progress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done {
+		return
+	}
+	d.done = true
+
+	d.clearLocked()
+	fmt.Fprint(d.out, d.newResultsLocked(p))
+	fmt.Fprintln(d.out, summaryLine(p))
+	fmt.Fprint(d.out, "\x1b[?25h")
+}
+
+// drawLocked replaces the live lines with the state in p; the caller holds d.mu
+func (d *Dashboard) drawLocked(p *Progress) {
+	width := terminalWidth(d.out)
+
+	var b strings.Builder
+	b.WriteString(clearSequence(d.lines))
+
+	// Finished items go above the live lines, so they stay in the scrollback
+	b.WriteString(d.newResultsLocked(p))
+
+	lines := 0
+	for _, item := range p.Items {
+		b.WriteString(itemLine(item, width))
+		b.WriteString("\n")
+		lines++
+	}
+	b.WriteString(overallLine(p, width))
+	b.WriteString("\n")
+	lines++
+
+	fmt.Fprint(d.out, b.String())
+	d.lines = lines
+	d.items = len(p.Items)
+	d.last = p
+	d.drawnAt = time.Now()
+}
+
+// clearLocked erases the live lines; the caller holds d.mu
+func (d *Dashboard) clearLocked() {
+	fmt.Fprint(d.out, clearSequence(d.lines))
+	d.lines = 0
+}
+
+// newResultsLocked renders the finished items not printed yet; the caller
+// holds d.mu
+func (d *Dashboard) newResultsLocked(p *Progress) string {
+	var b strings.Builder
+	for ; d.finished < len(p.Finished); d.finished++ {
+		b.WriteString(resultLine(p.Finished[d.finished], d.finished+1, p.Total))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// clearSequence moves the cursor up over n lines and erases to the end of the screen
+func clearSequence(n int) string {
+	if n == 0 {
+		return "\r\x1b[J"
+	}
+	return fmt.Sprintf("\r\x1b[%dA\x1b[J", n)
+}
+
+// itemLine renders the live line of an item in flight
+func itemLine(item ItemProgress, width int) string {
+	var status string
+	if item.TotalBytes > 0 {
+		fraction := float64(item.DownloadedBytes) / float64(item.TotalBytes)
+		speed, eta := "-", "-"
+		if item.Speed > 0 {
+			speed = FormatBytes(int64(item.Speed)) + "/s"
+			eta = item.ETA.Round(time.Second).String()
+		}
+		status = fmt.Sprintf(" %s %3.0f%% %12s ETA %-8s", bar(fraction, barLength(width)), fraction*100, speed, eta)
+	} else {
+		status = fmt.Sprintf(" %s %s", bar(0, barLength(width)), FormatBytes(item.DownloadedBytes))
+	}
+	if item.Retries > 0 {
+		status += fmt.Sprintf(" (retry %d)", item.Retries)
+	}
+
+	// Pad by display width, as CJK and emoji titles take two columns per
+	// rune; a line wider than the terminal wraps and breaks clearSequence
+	titleWidth := width - runewidth.StringWidth(status) - 3
+	title := truncate(item.Title, titleWidth)
+	padding := strings.Repeat(" ", max(titleWidth-runewidth.StringWidth(title), 0))
+	// On very narrow terminals the status alone is too wide
+	return truncate("  "+title+padding+status, width-1)
+}
+
+// overallLine renders the live line summarising the run
+func overallLine(p *Progress, width int) string {
+	done := p.Completed + p.Failed
+	fraction := 0.0
+	if p.Total > 0 {
+		fraction = float64(done) / float64(p.Total)
+	}
+
+	line := fmt.Sprintf("%s %d/%d", bar(fraction, barLength(width)), done, p.Total)
+	if p.Failed > 0 {
+		line += fmt.Sprintf(" (%d failed)", p.Failed)
+	}
+	line += fmt.Sprintf(" | %s", FormatBytes(p.GetDownloadedBytes()))
+	if throughput := p.GetThroughput(); throughput > 0 {
+		line += fmt.Sprintf(" at %s/s, ETA %v", FormatBytes(int64(throughput)), p.GetBytesETA().Round(time.Second))
+	}
+	line += fmt.Sprintf(" | %v elapsed", p.GetElapsedTime().Round(time.Second))
+
+	return truncate(line, width-1)
+}
+
+// bar renders a progress bar of the given length
+func bar(fraction float64, length int) string {
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * float64(length))
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", length-filled) + "]"
+}
+
+// barLength shrinks the bars on narrow terminals
+func barLength(width int) int {
+	if width < 60 {
+		return 10
+	}
+	return barWidth
+}
+
+// truncate shortens s to at most n terminal columns, marking the cut with an
+// ellipsis
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return runewidth.Truncate(s, n, "…")
+}
+
+// terminalWidth returns the width of the terminal, or defaultWidth if unknown
+func terminalWidth(out *os.File) int {
+	width, _, err := term.GetSize(int(out.Fd()))
+	if err != nil || width <= 0 {
+		return defaultWidth
+	}
+	return width
+}
@@ -0,0 +1,60 @@
+package progress
+
+import (
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestItemLineWidth(t *testing.T) {
+	titles := []string{
+		"Short",
+		"A long English title that certainly does not fit into a narrow terminal line",
+		"日本語のタイトルはそれぞれの文字が二列を使うのでとても長くなります",
+		"🎉🎉🎉 Emoji party 🎉🎉🎉 with a long tail of more text 🎸🎸🎸🎸🎸🎸🎸🎸",
+		"Ünïcode — mixed 한국어 and 中文 in one title that is long enough",
+	}
+	items := []ItemProgress{
+		{DownloadedBytes: 512 << 10, TotalBytes: 4 << 20, Speed: 1 << 20, ETA: 3e9},
+		{DownloadedBytes: 512 << 10, Retries: 2},
+	}
+
+	for _, width := range []int{40, 80, 120} {
+		for _, title := range titles {
+			for _, item := range items {
+				item.Title = title
+				line := itemLine(item, width)
+				// One column is left free so the cursor never wraps
+				if got := runewidth.StringWidth(line); got != width-1 {
+					t.Errorf("itemLine(%q, %d) is %d columns wide, want %d: %q", title, width, got, width-1, line)
+				}
+			}
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 4, "hel…"},
+		{"日本語", 6, "日本語"},
+		{"日本語", 5, "日本…"},
+		{"日本語", 4, "日…"},
+		{"🎉🎉", 3, "🎉…"},
+		{"hello", 0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+		if got := runewidth.StringWidth(truncate(tt.s, tt.n)); got > tt.n {
+			t.Errorf("truncate(%q, %d) is %d columns wide", tt.s, tt.n, got)
+		}
+	}
+}
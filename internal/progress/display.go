@@ -0,0 +1,131 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+// Display renders the progress of a run
+type Display interface {
+	// Update renders a progress snapshot; it is meant to be registered with
+	// AddCallback
+	Update(p *Progress)
+	// Write prints log output without corrupting the display
+	Write(b []byte) (int, error)
+	// Finish renders the final state; later updates are ignored
+	Finish(p *Progress)
+}
+
+// Progress output modes
+const (
+	ModeAuto  = "auto"
+	ModePlain = "plain"
+	ModeJSON  = "json"
+)
+
+// ValidateMode checks a progress output mode
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeAuto, ModePlain, ModeJSON:
+		return nil
+	default:
+		return errors.NewValidationError(fmt.Sprintf("invalid progress mode: %s", mode)).
+			WithDetails(fmt.Sprintf("Valid progress modes: %s, %s, %s", ModeAuto, ModePlain, ModeJSON))
+	}
+}
+
+// NewDisplay returns the display for a progress mode. ModeJSON writes events
+// to events and log output to out. ModeAuto picks the multi-line dashboard
+// when out is a terminal, and plain line-by-line output when it is not or
+// when NO_COLOR is set.
+func NewDisplay(out *os.File, mode string, events io.Writer) Display {
+	switch {
+	case mode == ModeJSON:
+		return NewJSONEmitter(events, out)
+	case mode == ModePlain || os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(out.Fd())):
+		return NewPlainDisplay(out)
+	default:
+		return NewDashboard(out)
+	}
+}
+
+// PlainDisplay prints one line per started and finished item, suitable for
+// logs and pipes
+type PlainDisplay struct {
+	out      io.Writer
+	started  map[string]bool
+	finished int
+	done     bool
+	mu       sync.Mutex
+}
+
+// NewPlainDisplay creates a plain display writing to out
+func NewPlainDisplay(out io.Writer) *PlainDisplay {
+	return &PlainDisplay{out: out, started: make(map[string]bool)}
+}
+
+// Update prints the items started or finished since the last update
+func (d *PlainDisplay) Update(p *Progress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.done {
+		d.printLocked(p)
+	}
+}
+
+// Write passes log output through
+func (d *PlainDisplay) Write(b []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.out.Write(b)
+}
+
+// Finish prints the remaining finished items and a summary line
+func (d *PlainDisplay) Finish(p *Progress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done {
+		return
+	}
+	d.done = true
+	d.printLocked(p)
+	fmt.Fprintln(d.out, summaryLine(p))
+}
+
+// printLocked prints the new events in p; the caller holds d.mu
+func (d *PlainDisplay) printLocked(p *Progress) {
+	for _, item := range p.Items {
+		if !d.started[item.ID] {
+			d.started[item.ID] = true
+			fmt.Fprintf(d.out, "⬇️  Started: %s\n", item.Title)
+		}
+	}
+
+	for ; d.finished < len(p.Finished); d.finished++ {
+		fmt.Fprintln(d.out, resultLine(p.Finished[d.finished], d.finished+1, p.Total))
+	}
+}
+
+// resultLine describes a finished item
+func resultLine(result ItemResult, n, total int) string {
+	if result.Err != nil {
+		return fmt.Sprintf("❌ [%d/%d] %s: %v", n, total, result.Title, result.Err)
+	}
+	return fmt.Sprintf("✅ [%d/%d] %s (%s in %v)", n, total, result.Title,
+		FormatBytes(result.Bytes), result.Duration.Round(time.Second))
+}
+
+// summaryLine describes the overall outcome of a run
+func summaryLine(p *Progress) string {
+	return fmt.Sprintf("📊 %d/%d done, %d failed, %s in %v",
+		p.Completed, p.Total, p.Failed, FormatBytes(p.GetDownloadedBytes()), p.GetElapsedTime().Round(time.Second))
+}
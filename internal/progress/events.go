@@ -0,0 +1,240 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+// Event names of the NDJSON progress stream
+const (
+	EventRunStarted     = "run_started"
+	EventVideoQueued    = "video_queued"
+	EventVideoStarted   = "video_started"
+	EventVideoProgress  = "video_progress"
+	EventVideoRetry     = "video_retry"
+	EventVideoCompleted = "video_completed"
+	EventVideoFailed    = "video_failed"
+	EventRunFinished    = "run_finished"
+)
+
+// jsonProgressInterval limits video_progress events to one per video per interval
+const jsonProgressInterval = time.Second
+
+// eventHeader is the part shared by all events
+type eventHeader struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+}
+
+// JSONEmitter writes progress as newline-delimited JSON events, one object
+// per line, for other programs to consume. Events are derived from the
+// differences between progress snapshots, so a dropped snapshot never loses
+// a video's start or completion. Every queued video ends with video_completed
+// or video_failed, including videos an interrupt kept from starting.
+type JSONEmitter struct {
+	events   io.Writer
+	logs     io.Writer
+	encoder  *json.Encoder
+	started  bool
+	queued   int
+	finished int
+	running  map[string]bool
+	ended    map[string]bool
+	retries  map[string]int
+	reported map[string]time.Time
+	err      error
+	done     bool
+	mu       sync.Mutex
+}
+
+// NewJSONEmitter creates an emitter writing events to events and passing log
+// output to logs
+func NewJSONEmitter(events, logs io.Writer) *JSONEmitter {
+	return &JSONEmitter{
+		events:   events,
+		logs:     logs,
+		encoder:  json.NewEncoder(events),
+		running:  make(map[string]bool),
+		ended:    make(map[string]bool),
+		retries:  make(map[string]int),
+		reported: make(map[string]time.Time),
+	}
+}
+
+// Update emits the events that happened since the previous snapshot
+func (e *JSONEmitter) Update(p *Progress) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.done {
+		e.emitChangesLocked(p)
+	}
+}
+
+// Write passes log output through; it never goes to the event stream
+func (e *JSONEmitter) Write(b []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.logs.Write(b)
+}
+
+// Finish emits the remaining events and run_finished with the totals
+func (e *JSONEmitter) Finish(p *Progress) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.done {
+		return
+	}
+	e.done = true
+	e.emitChangesLocked(p)
+
+	// Videos still queued were kept from starting by an interrupt
+	unfinished := 0
+	for _, item := range p.Queued {
+		if e.ended[item.ID] {
+			continue
+		}
+		e.ended[item.ID] = true
+		unfinished++
+		e.emitLocked(struct {
+			eventHeader
+			VideoID         string  `json:"video_id"`
+			Title           string  `json:"title"`
+			Error           string  `json:"error"`
+			Class           string  `json:"class,omitempty"`
+			DurationSeconds float64 `json:"duration_seconds"`
+		}{e.header(EventVideoFailed), item.ID, item.Title, "interrupted before starting", string(errors.ErrorTypeInterrupted), 0})
+	}
+
+	e.emitLocked(struct {
+		eventHeader
+		Total           int     `json:"total"`
+		Completed       int     `json:"completed"`
+		Failed          int     `json:"failed"`
+		Unfinished      int     `json:"unfinished"`
+		Bytes           int64   `json:"bytes"`
+		DurationSeconds float64 `json:"duration_seconds"`
+	}{e.header(EventRunFinished), p.Total, p.Completed, p.Failed, unfinished, p.GetDownloadedBytes(), p.GetElapsedTime().Seconds()})
+}
+
+// emitChangesLocked emits events for what changed in p; the caller holds e.mu
+func (e *JSONEmitter) emitChangesLocked(p *Progress) {
+	if !e.started {
+		e.started = true
+		e.emitLocked(struct {
+			eventHeader
+			Total int `json:"total"`
+		}{e.header(EventRunStarted), p.Total})
+	}
+
+	for ; e.queued < len(p.Queued); e.queued++ {
+		item := p.Queued[e.queued]
+		e.emitLocked(struct {
+			eventHeader
+			VideoID string `json:"video_id"`
+			Title   string `json:"title"`
+			Index   int    `json:"index"`
+		}{e.header(EventVideoQueued), item.ID, item.Title, e.queued + 1})
+	}
+
+	for _, item := range p.Items {
+		e.startLocked(item.ID, item.Title)
+
+		if item.Retries > e.retries[item.ID] {
+			e.retries[item.ID] = item.Retries
+			e.emitLocked(struct {
+				eventHeader
+				VideoID string `json:"video_id"`
+				Attempt int    `json:"attempt"`
+				Error   string `json:"error"`
+			}{e.header(EventVideoRetry), item.ID, item.Retries, errorString(item.LastError)})
+		}
+
+		if item.DownloadedBytes == 0 || time.Since(e.reported[item.ID]) < jsonProgressInterval {
+			continue
+		}
+		e.reported[item.ID] = time.Now()
+		e.emitLocked(struct {
+			eventHeader
+			VideoID         string  `json:"video_id"`
+			DownloadedBytes int64   `json:"downloaded_bytes"`
+			TotalBytes      int64   `json:"total_bytes"`
+			Speed           float64 `json:"speed_bytes_per_second"`
+			ETASeconds      float64 `json:"eta_seconds"`
+		}{e.header(EventVideoProgress), item.ID, item.DownloadedBytes, item.TotalBytes, item.Speed, item.ETA.Seconds()})
+	}
+
+	for ; e.finished < len(p.Finished); e.finished++ {
+		result := p.Finished[e.finished]
+		// An item may start and finish between two snapshots
+		e.startLocked(result.ID, result.Title)
+		delete(e.running, result.ID)
+		e.ended[result.ID] = true
+		delete(e.reported, result.ID)
+		delete(e.retries, result.ID)
+
+		if result.Err != nil {
+			e.emitLocked(struct {
+				eventHeader
+				VideoID         string  `json:"video_id"`
+				Title           string  `json:"title"`
+				Error           string  `json:"error"`
+				Class           string  `json:"class,omitempty"`
+				DurationSeconds float64 `json:"duration_seconds"`
+			}{e.header(EventVideoFailed), result.ID, result.Title, result.Err.Error(), string(errors.Class(result.Err)), result.Duration.Seconds()})
+			continue
+		}
+		e.emitLocked(struct {
+			eventHeader
+			VideoID         string  `json:"video_id"`
+			Title           string  `json:"title"`
+			Bytes           int64   `json:"bytes"`
+			DurationSeconds float64 `json:"duration_seconds"`
+		}{e.header(EventVideoCompleted), result.ID, result.Title, result.Bytes, result.Duration.Seconds()})
+	}
+}
+
+// startLocked emits video_started unless the item was already reported as
+// started; the caller holds e.mu
+func (e *JSONEmitter) startLocked(id, title string) {
+	if e.running[id] {
+		return
+	}
+	e.running[id] = true
+	e.emitLocked(struct {
+		eventHeader
+		VideoID string `json:"video_id"`
+		Title   string `json:"title"`
+	}{e.header(EventVideoStarted), id, title})
+}
+
+// emitLocked writes one event line; after a write error, e.g. a FIFO whose
+// reader went away, events are dropped. The caller holds e.mu.
+func (e *JSONEmitter) emitLocked(event interface{}) {
+	if e.err != nil {
+		return
+	}
+	if err := e.encoder.Encode(event); err != nil {
+		e.err = err
+		fmt.Fprintf(e.logs, "⚠️  Unable to write progress events, no more will be written: %v\n", err)
+	}
+}
+
+// header returns the shared fields of an event
+func (e *JSONEmitter) header(event string) eventHeader {
+	return eventHeader{Event: event, Time: time.Now().UTC()}
+}
+
+// errorString returns the message of err, or "" if it is nil
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,264 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+// decodeEvents parses an NDJSON event stream
+func decodeEvents(t *testing.T, stream string) []map[string]interface{} {
+	t.Helper()
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(stream))
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// summary returns "event" or "event:video_id" for each event
+func summary(events []map[string]interface{}) []string {
+	var names []string
+	for _, event := range events {
+		name := event["event"].(string)
+		if id, ok := event["video_id"]; ok {
+			name += ":" + id.(string)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// keys returns the sorted field names of an event
+func keys(event map[string]interface{}) []string {
+	var names []string
+	for name := range event {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestJSONEmitterEvents(t *testing.T) {
+	var out bytes.Buffer
+	emitter := NewJSONEmitter(&out, io.Discard)
+
+	queued := []QueuedItem{{ID: "aaaaaaaaaaa", Title: "First"}, {ID: "bbbbbbbbbbb", Title: "Second"}}
+	interrupted := errors.NewExternalError("interrupted").WithContext(errors.ContextClass, errors.ErrorTypeInterrupted)
+
+	snapshots := []*Progress{
+		{Total: 2, Queued: queued},
+		{Total: 2, Queued: queued, Items: []ItemProgress{
+			{ID: "aaaaaaaaaaa", Title: "First", DownloadedBytes: 100, TotalBytes: 1000, Speed: 50, ETA: 18 * time.Second},
+		}},
+		{Total: 2, Queued: queued, Items: []ItemProgress{
+			{ID: "aaaaaaaaaaa", Title: "First", Retries: 1, LastError: fmt.Errorf("connection reset")},
+		}},
+		// Second starts and is interrupted between snapshots
+		{Total: 2, Completed: 1, Failed: 1, Queued: queued, Finished: []ItemResult{
+			{ID: "aaaaaaaaaaa", Title: "First", Bytes: 1000, Duration: 2 * time.Second},
+			{ID: "bbbbbbbbbbb", Title: "Second", Err: interrupted},
+		}},
+	}
+	for _, snapshot := range snapshots[:3] {
+		emitter.Update(snapshot)
+	}
+	emitter.Finish(snapshots[3])
+	// Nothing is written after run_finished
+	emitter.Update(snapshots[3])
+	emitter.Finish(snapshots[3])
+
+	events := decodeEvents(t, out.String())
+
+	want := []string{
+		"run_started",
+		"video_queued:aaaaaaaaaaa",
+		"video_queued:bbbbbbbbbbb",
+		"video_started:aaaaaaaaaaa",
+		"video_progress:aaaaaaaaaaa",
+		"video_retry:aaaaaaaaaaa",
+		"video_completed:aaaaaaaaaaa",
+		"video_started:bbbbbbbbbbb",
+		"video_failed:bbbbbbbbbbb",
+		"run_finished",
+	}
+	if got := summary(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	fields := map[string][]string{
+		EventRunStarted:     {"event", "time", "total"},
+		EventVideoQueued:    {"event", "index", "time", "title", "video_id"},
+		EventVideoStarted:   {"event", "time", "title", "video_id"},
+		EventVideoProgress:  {"downloaded_bytes", "eta_seconds", "event", "speed_bytes_per_second", "time", "total_bytes", "video_id"},
+		EventVideoRetry:     {"attempt", "error", "event", "time", "video_id"},
+		EventVideoCompleted: {"bytes", "duration_seconds", "event", "time", "title", "video_id"},
+		EventVideoFailed:    {"class", "duration_seconds", "error", "event", "time", "title", "video_id"},
+		EventRunFinished:    {"bytes", "completed", "duration_seconds", "event", "failed", "time", "total", "unfinished"},
+	}
+	for _, event := range events {
+		name := event["event"].(string)
+		if got := keys(event); !reflect.DeepEqual(got, fields[name]) {
+			t.Errorf("%s fields = %v, want %v", name, got, fields[name])
+		}
+		if _, err := time.Parse(time.RFC3339Nano, event["time"].(string)); err != nil {
+			t.Errorf("%s time: %v", name, err)
+		}
+	}
+
+	if index := events[2]["index"]; index != 2.0 {
+		t.Errorf("index of the second video = %v, want 2", index)
+	}
+	if attempt := events[5]["attempt"]; attempt != 1.0 {
+		t.Errorf("retry attempt = %v, want 1", attempt)
+	}
+	if n := events[6]["bytes"]; n != 1000.0 {
+		t.Errorf("completed bytes = %v, want 1000", n)
+	}
+	failed := events[8]
+	if failed["error"] != "[external] interrupted" || failed["class"] != "interrupted" {
+		t.Errorf("failure = %v (%v), want the interruption", failed["error"], failed["class"])
+	}
+	finished := events[9]
+	if finished["total"] != 2.0 || finished["completed"] != 1.0 || finished["failed"] != 1.0 || finished["unfinished"] != 0.0 {
+		t.Errorf("run_finished totals = %v", finished)
+	}
+}
+
+func TestJSONEmitterUnfinished(t *testing.T) {
+	var out bytes.Buffer
+	emitter := NewJSONEmitter(&out, io.Discard)
+
+	// After an interrupt the first video completes, the second is stopped
+	// and the third never starts
+	queued := []QueuedItem{{ID: "aaaaaaaaaaa", Title: "First"}, {ID: "bbbbbbbbbbb", Title: "Second"}, {ID: "ccccccccccc", Title: "Third"}}
+	interrupted := errors.NewExternalError("interrupted").WithContext(errors.ContextClass, errors.ErrorTypeInterrupted)
+	emitter.Update(&Progress{Total: 3, Queued: queued, Items: []ItemProgress{{ID: "aaaaaaaaaaa", Title: "First"}, {ID: "bbbbbbbbbbb", Title: "Second"}}})
+	emitter.Finish(&Progress{Total: 3, Completed: 1, Failed: 1, Queued: queued, Finished: []ItemResult{
+		{ID: "aaaaaaaaaaa", Title: "First", Bytes: 1000},
+		{ID: "bbbbbbbbbbb", Title: "Second", Err: interrupted},
+	}})
+
+	events := decodeEvents(t, out.String())
+	want := []string{
+		"run_started",
+		"video_queued:aaaaaaaaaaa",
+		"video_queued:bbbbbbbbbbb",
+		"video_queued:ccccccccccc",
+		"video_started:aaaaaaaaaaa",
+		"video_started:bbbbbbbbbbb",
+		"video_completed:aaaaaaaaaaa",
+		"video_failed:bbbbbbbbbbb",
+		"video_failed:ccccccccccc",
+		"run_finished",
+	}
+	if got := summary(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	for _, failed := range events[7:9] {
+		if failed["class"] != "interrupted" {
+			t.Errorf("class of %v = %v, want interrupted", failed["video_id"], failed["class"])
+		}
+	}
+	if title := events[8]["title"]; title != "Third" {
+		t.Errorf("title of the video that never started = %v, want Third", title)
+	}
+	finished := events[9]
+	if finished["completed"] != 1.0 || finished["failed"] != 1.0 || finished["unfinished"] != 1.0 {
+		t.Errorf("run_finished totals = %v, want 1 completed, 1 failed, 1 unfinished", finished)
+	}
+}
+
+// slowWriter is an event consumer that takes its time reading
+type slowWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *slowWriter) Write(b []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *slowWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestJSONEmitterSlowConsumer(t *testing.T) {
+	const videos = 20
+
+	out := &slowWriter{}
+	emitter := NewJSONEmitter(out, io.Discard)
+	tracker := NewProgressTracker(videos)
+	tracker.AddCallback(emitter.Update)
+
+	var wg sync.WaitGroup
+	for i := 0; i < videos; i++ {
+		tracker.QueueItem(fmt.Sprintf("video%02d", i), "Title")
+	}
+	for i := 0; i < videos; i++ {
+		wg.Add(1)
+		go func(id string, fail bool) {
+			defer wg.Done()
+			tracker.StartItem(id, "Title")
+			// Far more updates than the consumer keeps up with; most are dropped
+			for n := int64(1); n <= 200; n++ {
+				tracker.UpdateItem(id, n*1024, 200*1024, 0, 0)
+			}
+			var err error
+			if fail {
+				err = errors.NewExternalError("interrupted")
+			}
+			tracker.FinishItem(id, err)
+		}(fmt.Sprintf("video%02d", i), i%4 == 0)
+	}
+	wg.Wait()
+	tracker.Stop()
+	emitter.Finish(tracker.GetProgress())
+
+	counts := make(map[string]int)
+	position := make(map[string]int)
+	for i, name := range summary(decodeEvents(t, out.String())) {
+		counts[name]++
+		position[name] = i
+	}
+
+	if counts[EventRunStarted] != 1 || counts[EventRunFinished] != 1 {
+		t.Errorf("run events = %d started, %d finished; want one each", counts[EventRunStarted], counts[EventRunFinished])
+	}
+	for i := 0; i < videos; i++ {
+		id := fmt.Sprintf("video%02d", i)
+		finish := EventVideoCompleted
+		if i%4 == 0 {
+			finish = EventVideoFailed
+		}
+		for _, event := range []string{EventVideoQueued, EventVideoStarted, finish} {
+			if n := counts[event+":"+id]; n != 1 {
+				t.Errorf("%s of %s emitted %d times, want once", event, id, n)
+			}
+		}
+		if position[EventVideoStarted+":"+id] > position[finish+":"+id] {
+			t.Errorf("%s of %s emitted before %s", finish, id, EventVideoStarted)
+		}
+	}
+}
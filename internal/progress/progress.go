@@ -13,7 +13,53 @@ type Progress struct {
 	Failed    int
 	Current   string
 	StartTime time.Time
-	mu        sync.RWMutex
+	// Queued lists the items of the run, in queue order
+	Queued []QueuedItem
+	// Items holds the byte-level progress of the items in flight, in start order
+	Items []ItemProgress
+	// Finished lists the items that completed or failed, in finish order
+	Finished []ItemResult
+	// FinishedBytes counts the bytes of items that completed
+	FinishedBytes int64
+	mu            sync.RWMutex
+}
+
+// QueuedItem is an item waiting to be processed
+type QueuedItem struct {
+	ID    string
+	Title string
+}
+
+// ItemResult is the outcome of a finished item
+type ItemResult struct {
+	ID       string
+	Title    string
+	Bytes    int64
+	Duration time.Duration
+	// Err is nil for completed items
+	Err error
+}
+
+// ItemProgress is the byte-level progress of a single item. Items made of
+// several streams (e.g. separate video and audio) accumulate across streams.
+type ItemProgress struct {
+	ID              string
+	Title           string
+	DownloadedBytes int64
+	TotalBytes      int64
+	// Speed is in bytes per second
+	Speed     float64
+	ETA       time.Duration
+	StartTime time.Time
+	// Retries counts the failed attempts so far
+	Retries int
+	// LastError is the error of the last failed attempt
+	LastError error
+
+	// streamBase holds the bytes of the item's already finished streams
+	streamBase int64
+	// streamDownloaded is the last seen byte count of the current stream
+	streamDownloaded int64
 }
 
 // ProgressCallback is a function type for progress updates
@@ -26,6 +72,8 @@ type ProgressTracker struct {
 	mu         sync.RWMutex
 	updateChan chan *Progress
 	done       chan bool
+	stopped    chan struct{}
+	stopOnce   sync.Once
 }
 
 // NewProgressTracker creates a new progress tracker
@@ -40,6 +88,7 @@ func NewProgressTracker(total int) *ProgressTracker {
 		callbacks:  make([]ProgressCallback, 0),
 		updateChan: make(chan *Progress, 10),
 		done:       make(chan bool),
+		stopped:    make(chan struct{}),
 	}
 	
 	// Start the progress updater
@@ -78,10 +127,7 @@ func (pt *ProgressTracker) IncrementCompleted(current string) {
 	pt.progress.Current = current
 	pt.mu.Unlock()
 	
-	select {
-	case pt.updateChan <- pt.getProgress():
-	default:
-	}
+	pt.deliver()
 }
 
 // IncrementFailed increments the failed count
@@ -91,12 +137,130 @@ func (pt *ProgressTracker) IncrementFailed(current string) {
 	pt.progress.Current = current
 	pt.mu.Unlock()
 	
+	pt.deliver()
+}
+
+// QueueItem adds an item to the run's queue
+func (pt *ProgressTracker) QueueItem(id, title string) {
+	pt.mu.Lock()
+	pt.progress.Queued = append(pt.progress.Queued, QueuedItem{ID: id, Title: title})
+	pt.mu.Unlock()
+
+	pt.deliver()
+}
+
+// StartItem begins tracking an item in flight
+func (pt *ProgressTracker) StartItem(id, title string) {
+	pt.mu.Lock()
+	pt.removeItemLocked(id)
+	pt.progress.Items = append(pt.progress.Items, ItemProgress{ID: id, Title: title, StartTime: time.Now()})
+	pt.progress.Current = title
+	pt.mu.Unlock()
+
+	pt.deliver()
+}
+
+// UpdateItem records the byte-level progress of the current stream of an
+// item. A byte count lower than the previous one means a new stream started.
+func (pt *ProgressTracker) UpdateItem(id string, downloaded, total int64, speed float64, eta time.Duration) {
+	pt.mu.Lock()
+	item := pt.findItemLocked(id)
+	if item == nil {
+		pt.mu.Unlock()
+		return
+	}
+
+	if downloaded < item.streamDownloaded {
+		item.streamBase += item.streamDownloaded
+	}
+	item.streamDownloaded = downloaded
+	item.DownloadedBytes = item.streamBase + downloaded
+	if total > 0 {
+		item.TotalBytes = item.streamBase + total
+	}
+	item.Speed = speed
+	item.ETA = eta
+	pt.mu.Unlock()
+
+	pt.notify()
+}
+
+// RetryItem records a failed attempt of an item that is about to be retried,
+// resetting its byte counts for the next attempt
+func (pt *ProgressTracker) RetryItem(id string, attempt int, err error) {
+	pt.mu.Lock()
+	if item := pt.findItemLocked(id); item != nil {
+		*item = ItemProgress{ID: item.ID, Title: item.Title, StartTime: item.StartTime, Retries: attempt, LastError: err}
+	}
+	pt.mu.Unlock()
+
+	pt.deliver()
+}
+
+// FinishItem stops tracking an item, counting it as completed, or as failed
+// when err is not nil
+func (pt *ProgressTracker) FinishItem(id string, err error) {
+	pt.mu.Lock()
+	result := ItemResult{ID: id, Err: err}
+	if item := pt.findItemLocked(id); item != nil {
+		result.Title = item.Title
+		result.Bytes = item.DownloadedBytes
+		result.Duration = time.Since(item.StartTime)
+	}
+	pt.removeItemLocked(id)
+
+	if err != nil {
+		pt.progress.Failed++
+	} else {
+		pt.progress.Completed++
+		pt.progress.FinishedBytes += result.Bytes
+	}
+	pt.progress.Current = result.Title
+	pt.progress.Finished = append(pt.progress.Finished, result)
+	pt.mu.Unlock()
+
+	pt.deliver()
+}
+
+// findItemLocked returns the item with the given ID; the caller holds pt.mu
+func (pt *ProgressTracker) findItemLocked(id string) *ItemProgress {
+	for i := range pt.progress.Items {
+		if pt.progress.Items[i].ID == id {
+			return &pt.progress.Items[i]
+		}
+	}
+	return nil
+}
+
+// removeItemLocked drops the item with the given ID; the caller holds pt.mu
+func (pt *ProgressTracker) removeItemLocked(id string) {
+	items := pt.progress.Items[:0]
+	for _, item := range pt.progress.Items {
+		if item.ID != id {
+			items = append(items, item)
+		}
+	}
+	pt.progress.Items = items
+}
+
+// notify sends the current progress to the update loop, dropping the
+// update if the loop is behind
+func (pt *ProgressTracker) notify() {
 	select {
 	case pt.updateChan <- pt.getProgress():
 	default:
 	}
 }
 
+// deliver sends the current progress to the update loop, waiting for room
+// so that items starting and finishing are never lost
+func (pt *ProgressTracker) deliver() {
+	select {
+	case pt.updateChan <- pt.getProgress():
+	case <-pt.done:
+	}
+}
+
 // GetProgress returns a copy of the current progress
 func (pt *ProgressTracker) GetProgress() *Progress {
 	return pt.getProgress()
@@ -108,39 +272,60 @@ func (pt *ProgressTracker) getProgress() *Progress {
 	defer pt.mu.RUnlock()
 	
 	return &Progress{
-		Total:     pt.progress.Total,
-		Completed: pt.progress.Completed,
-		Failed:    pt.progress.Failed,
-		Current:   pt.progress.Current,
-		StartTime: pt.progress.StartTime,
+		Total:         pt.progress.Total,
+		Completed:     pt.progress.Completed,
+		Failed:        pt.progress.Failed,
+		Current:       pt.progress.Current,
+		StartTime:     pt.progress.StartTime,
+		Queued:        append([]QueuedItem(nil), pt.progress.Queued...),
+		Items:         append([]ItemProgress(nil), pt.progress.Items...),
+		Finished:      append([]ItemResult(nil), pt.progress.Finished...),
+		FinishedBytes: pt.progress.FinishedBytes,
 	}
 }
 
-// Stop stops the progress tracker
+// Stop stops the progress tracker once the pending updates have been
+// passed to the callbacks
 func (pt *ProgressTracker) Stop() {
-	close(pt.done)
+	pt.stopOnce.Do(func() { close(pt.done) })
+	<-pt.stopped
 }
 
 // updateLoop handles progress updates
 func (pt *ProgressTracker) updateLoop() {
+	defer close(pt.stopped)
+
 	for {
 		select {
 		case progress := <-pt.updateChan:
-			pt.mu.RLock()
-			callbacks := make([]ProgressCallback, len(pt.callbacks))
-			copy(callbacks, pt.callbacks)
-			pt.mu.RUnlock()
-			
-			// Call all callbacks
-			for _, callback := range callbacks {
-				callback(progress)
-			}
+			pt.runCallbacks(progress)
 		case <-pt.done:
-			return
+			// Drain what was sent before Stop
+			for {
+				select {
+				case progress := <-pt.updateChan:
+					pt.runCallbacks(progress)
+				default:
+					return
+				}
+			}
 		}
 	}
 }
 
+// runCallbacks passes a progress update to all callbacks
+func (pt *ProgressTracker) runCallbacks(progress *Progress) {
+	pt.mu.RLock()
+	callbacks := make([]ProgressCallback, len(pt.callbacks))
+	copy(callbacks, pt.callbacks)
+	pt.mu.RUnlock()
+	
+	// Call all callbacks
+	for _, callback := range callbacks {
+		callback(progress)
+	}
+}
+
 // GetPercentage returns the completion percentage
 func (p *Progress) GetPercentage() float64 {
 	if p.Total == 0 {
@@ -177,16 +362,73 @@ func (p *Progress) GetRate() float64 {
 	return float64(p.Completed+p.Failed) / elapsed.Seconds()
 }
 
+// GetDownloadedBytes returns the bytes downloaded so far across all items
+func (p *Progress) GetDownloadedBytes() int64 {
+	downloaded := p.FinishedBytes
+	for _, item := range p.Items {
+		downloaded += item.DownloadedBytes
+	}
+	return downloaded
+}
+
+// GetThroughput returns the combined download speed of the items in flight,
+// in bytes per second
+func (p *Progress) GetThroughput() float64 {
+	var speed float64
+	for _, item := range p.Items {
+		speed += item.Speed
+	}
+	return speed
+}
+
+// GetBytesETA estimates the time until the items in flight finish, based on
+// their remaining bytes and the combined throughput
+func (p *Progress) GetBytesETA() time.Duration {
+	throughput := p.GetThroughput()
+	if throughput <= 0 {
+		return 0
+	}
+
+	var remaining int64
+	for _, item := range p.Items {
+		if item.TotalBytes > item.DownloadedBytes {
+			remaining += item.TotalBytes - item.DownloadedBytes
+		}
+	}
+	return time.Duration(float64(remaining) / throughput * float64(time.Second))
+}
+
 // String returns a string representation of the progress
 func (p *Progress) String() string {
 	percentage := p.GetPercentage()
 	elapsed := p.GetElapsedTime()
-	rate := p.GetRate()
 	
+	if throughput := p.GetThroughput(); throughput > 0 {
+		return fmt.Sprintf("[%d/%d] %.1f%% - %s - %s at %s/s, ETA %v - %v elapsed",
+			p.Completed+p.Failed, p.Total, percentage, p.Current, FormatBytes(p.GetDownloadedBytes()),
+			FormatBytes(int64(throughput)), p.GetBytesETA().Round(time.Second), elapsed.Round(time.Second))
+	}
+	
+	rate := p.GetRate()
 	return fmt.Sprintf("[%d/%d] %.1f%% - %s - %.1f items/sec - %v elapsed",
 		p.Completed+p.Failed, p.Total, percentage, p.Current, rate, elapsed.Round(time.Second))
 }
 
+// FormatBytes renders a byte count with a binary unit, e.g. "12.3 MiB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 // DefaultProgressCallback provides a default progress display
 func DefaultProgressCallback(p *Progress) {
 	fmt.Printf("\r%s", p.String())
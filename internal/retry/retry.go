@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/logger"
 )
 
 // RetryConfig holds retry configuration
@@ -16,6 +17,9 @@ type RetryConfig struct {
 	MaxDelay    time.Duration
 	Multiplier  float64
 	Jitter      bool
+	// OnRetry is called before waiting for the next attempt; when nil the
+	// failed attempt is logged
+	OnRetry func(attempt int, err error, delay time.Duration)
 }
 
 // DefaultRetryConfig returns a default retry configuration
@@ -112,8 +116,12 @@ func RetryWithContext(ctx context.Context, fn RetryableFuncWithContext, config *
 		// Calculate delay
 		delay := calculateDelay(attempt, config)
 		
-		// Log retry attempt
-		fmt.Printf("Attempt %d failed: %v. Retrying in %v...\n", attempt, err, delay)
+		// Report retry attempt
+		if config.OnRetry != nil {
+			config.OnRetry(attempt, err, delay)
+		} else {
+			logger.Warn("Attempt %d failed: %v. Retrying in %v...", attempt, err, delay)
+		}
 		
 		// Wait before retry
 		select {
@@ -133,10 +141,15 @@ func IsRetryableError(err error) bool {
 		return false
 	}
 	
+	// Classified download failures are only retried when transient
+	if class := errors.Class(err); class != "" {
+		return class == errors.ErrorTypeTransient
+	}
+
 	// Check if it's a YeeTrapError
 	if ytErr, ok := err.(*errors.YeeTrapError); ok {
 		switch ytErr.Type {
-		case errors.ErrorTypeNetwork:
+		case errors.ErrorTypeNetwork, errors.ErrorTypeTransient:
 			return true
 		case errors.ErrorTypeAPI:
 			// Check for specific API errors that should be retried
@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+)
+
+// classified returns an error of a download failure class, as the yt-dlp
+// backend reports it
+func classified(class errors.ErrorType, message string) error {
+	return errors.Wrap(fmt.Errorf("exit status 1"), class, message).WithContext(errors.ContextClass, class)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "transient", err: classified(errors.ErrorTypeTransient, "read timed out"), want: true},
+		{name: "rate limited", err: classified(errors.ErrorTypeRateLimited, "HTTP Error 429"), want: false},
+		{name: "unavailable", err: classified(errors.ErrorTypeUnavailable, "Video unavailable"), want: false},
+		{name: "private", err: classified(errors.ErrorTypePrivate, "Private video"), want: false},
+		{name: "auth required", err: classified(errors.ErrorTypeAuthRequired, "Sign in to confirm your age"), want: false},
+		{name: "geo blocked", err: classified(errors.ErrorTypeGeoBlocked, "not available in your country"), want: false},
+		{name: "disk full", err: classified(errors.ErrorTypeDiskFull, "No space left on device"), want: false},
+		// The class decides, whatever the message says
+		{name: "unclassified download failure mentioning network", err: classified(errors.ErrorTypeExternal, "network settings are wrong"), want: false},
+		{name: "wrapped transient", err: errors.WrapExternal(classified(errors.ErrorTypeTransient, "connection reset"), "failed to download"), want: true},
+		{name: "network error", err: errors.NewNetworkError("unable to reach server"), want: true},
+		{name: "external timeout", err: errors.NewExternalError("connection timeout"), want: true},
+		{name: "validation", err: errors.NewValidationError("invalid quality"), want: false},
+		{name: "plain temporary", err: fmt.Errorf("temporary failure"), want: true},
+		{name: "plain other", err: fmt.Errorf("permission denied"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryWithContextStopsOnPermanentFailure(t *testing.T) {
+	config := &RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1}
+
+	attempts := 0
+	err := RetryWithContext(context.Background(), func(context.Context) error {
+		attempts++
+		return classified(errors.ErrorTypeUnavailable, "Video unavailable")
+	}, config)
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+	if errors.Class(err) != errors.ErrorTypeUnavailable {
+		t.Errorf("error class = %q, want %q", errors.Class(err), errors.ErrorTypeUnavailable)
+	}
+
+	attempts = 0
+	err = RetryWithContext(context.Background(), func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return classified(errors.ErrorTypeTransient, "connection reset")
+		}
+		return nil
+	}, config)
+	if err != nil || attempts != 3 {
+		t.Errorf("RetryWithContext() = %v after %d attempts, want success after 3", err, attempts)
+	}
+}
@@ -15,6 +15,12 @@ var channelIDPattern = regexp.MustCompile(`^UC[a-zA-Z0-9_-]{22}$`)
 // YouTube video ID pattern
 var videoIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`)
 
+// YouTube playlist ID pattern (e.g. PL..., UU..., OLAK5uy_...)
+var playlistIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{13,64}$`)
+
+// Account profile name pattern
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
 // ValidateChannelID validates a YouTube channel ID
 func ValidateChannelID(channelID string) error {
 	if channelID == "" {
@@ -43,6 +49,34 @@ func ValidateVideoID(videoID string) error {
 	return nil
 }
 
+// ValidatePlaylistID validates a YouTube playlist ID
+func ValidatePlaylistID(playlistID string) error {
+	if playlistID == "" {
+		return errors.NewValidationError("playlist ID cannot be empty")
+	}
+	
+	if !playlistIDPattern.MatchString(playlistID) {
+		return errors.NewValidationError(fmt.Sprintf("invalid playlist ID format: %s", playlistID)).
+			WithDetails("Pass a playlist ID such as PLxxxxxxxxxxxxxxxx or a URL containing list=")
+	}
+	
+	return nil
+}
+
+// ValidateProfileName validates an account profile name
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return errors.NewValidationError("profile name cannot be empty")
+	}
+	
+	if !profileNamePattern.MatchString(name) {
+		return errors.NewValidationError(fmt.Sprintf("invalid profile name: %s", name)).
+			WithDetails("Profile names may contain letters, digits, '.', '_' and '-' (up to 64 characters)")
+	}
+	
+	return nil
+}
+
 // ValidateQuality validates video quality parameter
 func ValidateQuality(quality string) error {
 	if quality == "" {
@@ -56,7 +90,7 @@ func ValidateQuality(quality string) error {
 	}
 	
 	return errors.NewValidationError(fmt.Sprintf("invalid quality: %s", quality)).
-		WithDetails(fmt.Sprintf("Supported qualities: %s", strings.Join(constants.SupportedQualities, ", ")))
+		WithDetails(fmt.Sprintf("Supported qualities: %s; use --format or --format-profile for anything else", strings.Join(constants.SupportedQualities, ", ")))
 }
 
 // ValidateConcurrency validates concurrent download count
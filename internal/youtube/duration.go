@@ -0,0 +1,67 @@
+package youtube
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// parseISODuration parses the ISO 8601 durations used by the YouTube API,
+// e.g. "PT1H2M3S", "P1DT2H" or "P0D"
+func parseISODuration(s string) (time.Duration, error) {
+	if len(s) < 2 || s[0] != 'P' {
+		return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+	}
+
+	var total time.Duration
+	inTime := false
+	components := 0
+	number := ""
+
+	for _, r := range s[1:] {
+		switch {
+		case r == 'T':
+			// A time designator needs components after it and appears once
+			if inTime || number != "" {
+				return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+			}
+			inTime = true
+			components = 0
+		case (r >= '0' && r <= '9') || r == '.':
+			number += string(r)
+		default:
+			if number == "" {
+				return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+			}
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+			}
+			number = ""
+
+			var unit time.Duration
+			switch {
+			case r == 'W' && !inTime:
+				unit = 7 * 24 * time.Hour
+			case r == 'D' && !inTime:
+				unit = 24 * time.Hour
+			case r == 'H' && inTime:
+				unit = time.Hour
+			case r == 'M' && inTime:
+				unit = time.Minute
+			case r == 'S' && inTime:
+				unit = time.Second
+			default:
+				return 0, fmt.Errorf("unsupported ISO 8601 duration component %q in %q", r, s)
+			}
+			total += time.Duration(value * float64(unit))
+			components++
+		}
+	}
+
+	if number != "" || components == 0 {
+		return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,64 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"P0D", 0},
+		{"PT0S", 0},
+		{"PT15S", 15 * time.Second},
+		{"PT1H2M3S", time.Hour + 2*time.Minute + 3*time.Second},
+		{"PT10M", 10 * time.Minute},
+		{"P1DT2H", 26 * time.Hour},
+		{"P1W", 7 * 24 * time.Hour},
+		{"P2DT0H0M1S", 48*time.Hour + time.Second},
+		{"PT0.5S", 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseISODuration(tt.value)
+			if err != nil {
+				t.Fatalf("parseISODuration() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseISODuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseISODurationInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"P",
+		"PT",
+		"P1DT",
+		"1H",
+		"T1H",
+		"PT1H2",
+		"PTM",
+		"PT1HT2M",
+		"P1TH",
+		"P1H",
+		"PT1D",
+		"P1M",
+		"PT1.2.3S",
+		"PT1X",
+		"pt1h",
+	}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			if got, err := parseISODuration(value); err == nil {
+				t.Errorf("parseISODuration() = %v, want an error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
+)
+
+// Playlist represents a YouTube playlist
+type Playlist struct {
+	ID            string
+	Title         string
+	Description   string
+	PublishedAt   time.Time
+	PrivacyStatus string
+	ItemCount     int64
+}
+
+// playlistParts are the playlists.list parts fetched for a Playlist
+var playlistParts = []string{"snippet", "contentDetails", "status"}
+
+// ListPlaylists lists the playlists of a channel, or of the authenticated
+// user if channelID is empty. The latter includes private playlists.
+func (s *Service) ListPlaylists(ctx context.Context, channelID string) ([]Playlist, error) {
+	if channelID == "" && s.apiKeyMode {
+		return nil, errOAuthRequired
+	}
+
+	var playlists []Playlist
+	nextPageToken := ""
+
+	for {
+		call := s.client.Playlists.List(playlistParts).
+			MaxResults(constants.MaxVideosPerPage)
+
+		if channelID == "" {
+			call = call.Mine(true)
+		} else {
+			call = call.ChannelId(channelID)
+		}
+
+		if nextPageToken != "" {
+			call = call.PageToken(nextPageToken)
+		}
+
+		response, err := call.Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving playlists: %w", err)
+		}
+
+		for _, item := range response.Items {
+			playlist := Playlist{ID: item.Id}
+			if item.Snippet != nil {
+				playlist.Title = item.Snippet.Title
+				playlist.Description = item.Snippet.Description
+				playlist.PublishedAt = parseTime(item.Snippet.PublishedAt)
+			}
+			if item.Status != nil {
+				playlist.PrivacyStatus = item.Status.PrivacyStatus
+			}
+			if item.ContentDetails != nil {
+				playlist.ItemCount = item.ContentDetails.ItemCount
+			}
+			playlists = append(playlists, playlist)
+		}
+
+		nextPageToken = response.NextPageToken
+		if nextPageToken == "" {
+			break
+		}
+	}
+
+	return playlists, nil
+}
+
+// ListPlaylistVideos lists the videos of a playlist in playlist order, up to
+// maxResults (0 means all). Each video records its playlist position. The
+// filter is applied to each enriched page before counting towards maxResults.
+// Cancelling ctx stops the listing between API calls.
+func (s *Service) ListPlaylistVideos(ctx context.Context, playlistID string, maxResults int64, filter VideoFilter) ([]Video, error) {
+	var videos []Video
+	nextPageToken := ""
+
+	for {
+		call := s.client.PlaylistItems.List([]string{"snippet"}).
+			PlaylistId(playlistID).
+			MaxResults(constants.MaxVideosPerPage)
+
+		if nextPageToken != "" {
+			call = call.PageToken(nextPageToken)
+		}
+
+		response, err := call.Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving playlist items: %w", err)
+		}
+
+		page := make([]Video, 0, len(response.Items))
+		for _, item := range response.Items {
+			page = append(page, Video{
+				ID:            item.Snippet.ResourceId.VideoId,
+				Title:         item.Snippet.Title,
+				Description:   item.Snippet.Description,
+				PublishedAt:   parseTime(item.Snippet.PublishedAt),
+				ChannelID:     item.Snippet.ChannelId,
+				ChannelTitle:  item.Snippet.ChannelTitle,
+				PlaylistIndex: int(item.Snippet.Position) + 1,
+			})
+		}
+
+		// A page holds at most 50 items, so enriching costs one videos.list call per page
+		if _, err := s.enrichVideos(ctx, page); err != nil {
+			return nil, err
+		}
+
+		for _, video := range page {
+			if filter != nil && !filter(video) {
+				continue
+			}
+			videos = append(videos, video)
+
+			if maxResults > 0 && int64(len(videos)) >= maxResults {
+				return videos, nil
+			}
+		}
+
+		nextPageToken = response.NextPageToken
+		if nextPageToken == "" {
+			break
+		}
+	}
+
+	return videos, nil
+}
@@ -0,0 +1,316 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/errors"
+	"github.com/AlienFacepalm/YeeTrap/internal/validation"
+)
+
+// RefKind is the kind of YouTube resource a reference points to
+type RefKind string
+
+// Reference kinds
+const (
+	RefChannel  RefKind = "channel"
+	RefVideo    RefKind = "video"
+	RefPlaylist RefKind = "playlist"
+)
+
+// Ref is a parsed reference to a channel, video or playlist. Channel
+// references given as a handle or legacy name carry no ID yet; resolve them
+// with Service.ResolveChannel.
+type Ref struct {
+	Kind RefKind
+	// ID is the channel, video or playlist ID, when known without an API call
+	ID string
+	// Handle is a channel handle without the leading "@"
+	Handle string
+	// Name is a legacy /user/ or /c/ channel name
+	Name string
+	// PlaylistID is the playlist a watch URL was opened from (list=), if any
+	PlaylistID string
+}
+
+// YouTube handles: 3-30 characters of letters, digits, '_', '-' and '.'
+var handlePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,30}$`)
+
+// Legacy /user/ and /c/ channel names
+var channelNamePattern = regexp.MustCompile(`^[^/?#\s]{1,100}$`)
+
+// String returns the reference in the form the user would recognize
+func (r Ref) String() string {
+	switch {
+	case r.ID != "":
+		return r.ID
+	case r.Handle != "":
+		return "@" + r.Handle
+	default:
+		return r.Name
+	}
+}
+
+// ParseRef parses a channel, video or playlist reference. Accepted forms:
+//
+//	UC...                           channel ID
+//	@handle, youtube.com/@handle    channel handle
+//	youtube.com/channel/UC...       channel ID
+//	youtube.com/c/name, /user/name  legacy channel name
+//	11-character video ID, youtube.com/watch?v=..., youtu.be/...,
+//	youtube.com/shorts/..., /live/..., /embed/...
+//	playlist ID, youtube.com/playlist?list=...
+func ParseRef(value string) (Ref, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Ref{}, errors.NewValidationError("reference cannot be empty")
+	}
+
+	if strings.HasPrefix(value, "@") {
+		return parseHandle(value[1:], value)
+	}
+
+	if looksLikeURL(value) {
+		return parseURL(value)
+	}
+
+	switch {
+	case validation.ValidateChannelID(value) == nil:
+		return Ref{Kind: RefChannel, ID: value}, nil
+	case validation.ValidateVideoID(value) == nil:
+		return Ref{Kind: RefVideo, ID: value}, nil
+	case validation.ValidatePlaylistID(value) == nil:
+		return Ref{Kind: RefPlaylist, ID: value}, nil
+	}
+
+	return Ref{}, invalidRef(value)
+}
+
+// ParseChannelRef parses a reference that must point to a channel
+func ParseChannelRef(value string) (Ref, error) {
+	return parseKind(value, RefChannel)
+}
+
+// ParseVideoRef parses a reference that must point to a video
+func ParseVideoRef(value string) (Ref, error) {
+	return parseKind(value, RefVideo)
+}
+
+// ParseVideoID extracts a video ID from a video reference. Bare values are
+// checked with validation.ValidateVideoID so malformed IDs get a precise error.
+func ParseVideoID(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if !looksLikeURL(value) && !strings.HasPrefix(value, "@") {
+		if err := validation.ValidateVideoID(value); err != nil {
+			return "", err
+		}
+		return value, nil
+	}
+
+	ref, err := ParseVideoRef(value)
+	if err != nil {
+		return "", err
+	}
+	return ref.ID, nil
+}
+
+// ParsePlaylistID extracts a playlist ID from a playlist reference, or from a
+// watch URL that was opened from a playlist
+func ParsePlaylistID(value string) (string, error) {
+	ref, err := ParseRef(value)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.Kind == RefVideo && ref.PlaylistID != "" {
+		return ref.PlaylistID, nil
+	}
+	if ref.Kind != RefPlaylist {
+		return "", wrongKind(value, ref.Kind, RefPlaylist)
+	}
+	return ref.ID, nil
+}
+
+// parseKind parses a reference and checks its kind
+func parseKind(value string, kind RefKind) (Ref, error) {
+	ref, err := ParseRef(value)
+	if err != nil {
+		return Ref{}, err
+	}
+	if ref.Kind != kind {
+		return Ref{}, wrongKind(value, ref.Kind, kind)
+	}
+	return ref, nil
+}
+
+// looksLikeURL reports whether value is a URL, with or without scheme
+func looksLikeURL(value string) bool {
+	if strings.Contains(value, "://") {
+		return true
+	}
+	host, _, _ := strings.Cut(value, "/")
+	host, _, _ = strings.Cut(host, "?")
+	return isYouTubeHost(host)
+}
+
+// isYouTubeHost reports whether host serves YouTube pages
+func isYouTubeHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "youtu.be" || host == "youtube.com" || strings.HasSuffix(host, ".youtube.com")
+}
+
+// parseURL parses a YouTube URL into a reference
+func parseURL(value string) (Ref, error) {
+	raw := value
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || !isYouTubeHost(u.Hostname()) {
+		return Ref{}, invalidRef(value)
+	}
+
+	query := u.Query()
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+
+	if strings.EqualFold(u.Hostname(), "youtu.be") {
+		if len(segments) == 0 {
+			return Ref{}, invalidRef(value)
+		}
+		return videoRef(segments[0], query.Get("list"))
+	}
+
+	if len(segments) == 0 {
+		return Ref{}, invalidRef(value)
+	}
+
+	switch first := segments[0]; {
+	case first == "watch":
+		if v := query.Get("v"); v != "" {
+			return videoRef(v, query.Get("list"))
+		}
+		if list := query.Get("list"); list != "" {
+			return playlistRef(list)
+		}
+	case first == "playlist":
+		if list := query.Get("list"); list != "" {
+			return playlistRef(list)
+		}
+	case first == "shorts" || first == "live" || first == "embed" || first == "v":
+		if len(segments) > 1 {
+			return videoRef(segments[1], query.Get("list"))
+		}
+	case strings.HasPrefix(first, "@"):
+		return parseHandle(strings.TrimPrefix(first, "@"), value)
+	case first == "channel":
+		if len(segments) > 1 {
+			if err := validation.ValidateChannelID(segments[1]); err != nil {
+				return Ref{}, err
+			}
+			return Ref{Kind: RefChannel, ID: segments[1]}, nil
+		}
+	case first == "c" || first == "user":
+		if len(segments) > 1 {
+			return channelNameRef(segments[1], value)
+		}
+	}
+
+	return Ref{}, invalidRef(value)
+}
+
+// parseHandle builds a channel reference from a handle without "@"
+func parseHandle(handle, original string) (Ref, error) {
+	if !handlePattern.MatchString(handle) {
+		return Ref{}, errors.NewValidationError(fmt.Sprintf("invalid channel handle: %s", original)).
+			WithDetails("Handles are 3-30 letters, digits, '_', '-' or '.', e.g. @GoogleDevelopers")
+	}
+	return Ref{Kind: RefChannel, Handle: handle}, nil
+}
+
+// channelNameRef builds a channel reference from a legacy channel name
+func channelNameRef(name, original string) (Ref, error) {
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if !channelNamePattern.MatchString(name) {
+		return Ref{}, invalidRef(original)
+	}
+	return Ref{Kind: RefChannel, Name: name}, nil
+}
+
+// videoRef builds a video reference, remembering the playlist if any
+func videoRef(id, list string) (Ref, error) {
+	if err := validation.ValidateVideoID(id); err != nil {
+		return Ref{}, err
+	}
+
+	ref := Ref{Kind: RefVideo, ID: id}
+	if list != "" && validation.ValidatePlaylistID(list) == nil {
+		ref.PlaylistID = list
+	}
+	return ref, nil
+}
+
+// playlistRef builds a playlist reference
+func playlistRef(id string) (Ref, error) {
+	if err := validation.ValidatePlaylistID(id); err != nil {
+		return Ref{}, err
+	}
+	return Ref{Kind: RefPlaylist, ID: id}, nil
+}
+
+// invalidRef returns the error for an unrecognized reference
+func invalidRef(value string) error {
+	return errors.NewValidationError(fmt.Sprintf("unrecognized channel, video or playlist reference: %s", value)).
+		WithDetails("Use an ID (UC..., an 11-character video ID, PL...), an @handle, or a youtube.com / youtu.be URL")
+}
+
+// wrongKind returns the error for a reference of an unexpected kind
+func wrongKind(value string, got, want RefKind) error {
+	return errors.NewValidationError(fmt.Sprintf("%s is a %s, expected a %s", value, got, want))
+}
+
+// ResolveChannel returns the channel ID of a channel reference
+func (s *Service) ResolveChannel(ctx context.Context, ref Ref) (string, error) {
+	if ref.Kind != RefChannel {
+		return "", wrongKind(ref.String(), ref.Kind, RefChannel)
+	}
+	if ref.ID != "" {
+		return ref.ID, nil
+	}
+
+	if ref.Handle != "" {
+		return s.lookupChannel(ctx, ref, s.client.Channels.List([]string{"id"}).ForHandle(ref.Handle))
+	}
+
+	// Legacy /user/ names map to forUsername; /c/ names have no lookup of
+	// their own but usually match the channel's handle
+	id, err := s.lookupChannel(ctx, ref, s.client.Channels.List([]string{"id"}).ForUsername(ref.Name))
+	if err == nil {
+		return id, nil
+	}
+	if handlePattern.MatchString(ref.Name) {
+		return s.lookupChannel(ctx, ref, s.client.Channels.List([]string{"id"}).ForHandle(ref.Name))
+	}
+	return "", err
+}
+
+// lookupChannel runs a channels.list lookup and returns the single channel ID
+func (s *Service) lookupChannel(ctx context.Context, ref Ref, call *youtube.ChannelsListCall) (string, error) {
+	response, err := call.Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("error resolving channel %s: %w", ref, err)
+	}
+
+	if len(response.Items) == 0 {
+		return "", errors.NewValidationError(fmt.Sprintf("channel not found: %s", ref))
+	}
+
+	return response.Items[0].Id, nil
+}
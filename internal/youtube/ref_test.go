@@ -0,0 +1,114 @@
+package youtube
+
+import "testing"
+
+const (
+	testChannelID  = "UC_x5XG1OV2P6uZZ5FSM9Ttw"
+	testVideoID    = "dQw4w9WgXcQ"
+	testPlaylistID = "PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Ref
+	}{
+		// Channels
+		{testChannelID, Ref{Kind: RefChannel, ID: testChannelID}},
+		{"@GoogleDevelopers", Ref{Kind: RefChannel, Handle: "GoogleDevelopers"}},
+		{"  @google.dev-team_1  ", Ref{Kind: RefChannel, Handle: "google.dev-team_1"}},
+		{"https://www.youtube.com/@GoogleDevelopers", Ref{Kind: RefChannel, Handle: "GoogleDevelopers"}},
+		{"youtube.com/@GoogleDevelopers/videos", Ref{Kind: RefChannel, Handle: "GoogleDevelopers"}},
+		{"https://www.youtube.com/channel/" + testChannelID, Ref{Kind: RefChannel, ID: testChannelID}},
+		{"https://m.youtube.com/channel/" + testChannelID + "/videos?view=0", Ref{Kind: RefChannel, ID: testChannelID}},
+		{"https://www.youtube.com/c/GoogleDevelopers", Ref{Kind: RefChannel, Name: "GoogleDevelopers"}},
+		{"https://www.youtube.com/user/GoogleDevelopers", Ref{Kind: RefChannel, Name: "GoogleDevelopers"}},
+		{"https://www.youtube.com/c/Caf%C3%A9Channel", Ref{Kind: RefChannel, Name: "CaféChannel"}},
+
+		// Videos
+		{testVideoID, Ref{Kind: RefVideo, ID: testVideoID}},
+		{"https://www.youtube.com/watch?v=" + testVideoID, Ref{Kind: RefVideo, ID: testVideoID}},
+		{"youtube.com/watch?v=" + testVideoID + "&t=42s", Ref{Kind: RefVideo, ID: testVideoID}},
+		{"https://www.youtube.com/watch?v=" + testVideoID + "&list=" + testPlaylistID, Ref{Kind: RefVideo, ID: testVideoID, PlaylistID: testPlaylistID}},
+		{"https://youtu.be/" + testVideoID, Ref{Kind: RefVideo, ID: testVideoID}},
+		{"youtu.be/" + testVideoID + "?si=abc&list=" + testPlaylistID, Ref{Kind: RefVideo, ID: testVideoID, PlaylistID: testPlaylistID}},
+		{"https://www.youtube.com/shorts/" + testVideoID, Ref{Kind: RefVideo, ID: testVideoID}},
+		{"https://www.youtube.com/live/" + testVideoID + "?feature=share", Ref{Kind: RefVideo, ID: testVideoID}},
+		{"https://www.youtube.com/embed/" + testVideoID, Ref{Kind: RefVideo, ID: testVideoID}},
+		{"https://music.youtube.com/watch?v=" + testVideoID, Ref{Kind: RefVideo, ID: testVideoID}},
+
+		// Playlists
+		{testPlaylistID, Ref{Kind: RefPlaylist, ID: testPlaylistID}},
+		{"https://www.youtube.com/playlist?list=" + testPlaylistID, Ref{Kind: RefPlaylist, ID: testPlaylistID}},
+		{"https://www.youtube.com/watch?list=" + testPlaylistID, Ref{Kind: RefPlaylist, ID: testPlaylistID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRef(tt.value)
+			if err != nil {
+				t.Fatalf("ParseRef() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRef() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRefInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"not a reference",
+		"@ab",
+		"@has space",
+		"https://example.com/watch?v=" + testVideoID,
+		"https://www.youtube.com/",
+		"https://www.youtube.com/watch",
+		"https://www.youtube.com/watch?v=short",
+		"https://youtu.be/",
+		"https://www.youtube.com/shorts/",
+		"https://www.youtube.com/channel/UCshort",
+		"https://www.youtube.com/playlist?list=",
+		"https://www.youtube.com/some/deep/path",
+		"https://www.youtube.com/GoogleDevelopers",
+		"https://www.youtube.com/results?search_query=x",
+		"https://www.youtube.com/feed",
+		"https://www.youtube.com/about",
+		"ftp://youtube.com/%zz",
+	}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			if got, err := ParseRef(value); err == nil {
+				t.Errorf("ParseRef() = %+v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	if _, err := ParseChannelRef("https://youtu.be/" + testVideoID); err == nil {
+		t.Error("ParseChannelRef() accepted a video URL")
+	}
+	if _, err := ParseVideoRef("@GoogleDevelopers"); err == nil {
+		t.Error("ParseVideoRef() accepted a handle")
+	}
+
+	id, err := ParseVideoID("https://www.youtube.com/shorts/" + testVideoID)
+	if err != nil || id != testVideoID {
+		t.Errorf("ParseVideoID() = %q, %v; want %q", id, err, testVideoID)
+	}
+	if _, err := ParseVideoID("tooShort"); err == nil {
+		t.Error("ParseVideoID() accepted a malformed ID")
+	}
+
+	list, err := ParsePlaylistID("https://www.youtube.com/watch?v=" + testVideoID + "&list=" + testPlaylistID)
+	if err != nil || list != testPlaylistID {
+		t.Errorf("ParsePlaylistID() = %q, %v; want %q", list, err, testPlaylistID)
+	}
+	if _, err := ParsePlaylistID("https://youtu.be/" + testVideoID); err == nil {
+		t.Error("ParsePlaylistID() accepted a video URL without a playlist")
+	}
+}
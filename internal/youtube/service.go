@@ -3,23 +3,67 @@ package youtube
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
+
+	"github.com/AlienFacepalm/YeeTrap/internal/constants"
 )
 
 // Service wraps the YouTube API service
 type Service struct {
 	client *youtube.Service
+	// apiKeyMode is set when the service uses an API key instead of OAuth2,
+	// so only public data is visible and "mine" requests are impossible
+	apiKeyMode bool
 }
 
-// Video represents a YouTube video
+// errOAuthRequired explains that the authenticated user's own channel is unknown in API-key mode
+var errOAuthRequired = fmt.Errorf("the authenticated user's channel is not available with an API key: " +
+	"pass a channel ID with --channel, or drop --api-key and run 'yeetrap auth'")
+
+// Video represents a YouTube video. The basic fields come from the playlist
+// listing; the remaining metadata is filled in through videos.list.
 type Video struct {
-	ID          string
-	Title       string
-	Description string
-	PublishedAt string
+	ID           string
+	Title        string
+	Description  string
+	PublishedAt  time.Time
+	ChannelID    string
+	ChannelTitle string
+	// PlaylistIndex is the 1-based position in the playlist the video was
+	// listed from, or 0 if it was not listed from a playlist
+	PlaylistIndex int
+
+	Duration             time.Duration
+	PrivacyStatus        string
+	UploadStatus         string
+	Tags                 []string
+	CategoryID           string
+	DefaultLanguage      string
+	Definition           string
+	HasCaptions          bool
+	LiveBroadcastContent string
+	ViewCount            uint64
+	LikeCount            uint64
+}
+
+// VideoFilter decides whether a listed video is kept. A nil filter keeps all.
+type VideoFilter func(Video) bool
+
+// videoParts are the videos.list parts fetched to enrich a Video.
+// videos.list costs one quota unit per call regardless of the parts requested.
+var videoParts = []string{"snippet", "contentDetails", "status", "statistics"}
+
+// Caption represents a caption track of a video
+type Caption struct {
+	ID        string
+	Language  string
+	Name      string
+	TrackKind string
 }
 
 // NewService creates a new YouTube service
@@ -33,27 +77,34 @@ func NewService(httpClient *http.Client) (*Service, error) {
 	return &Service{client: service}, nil
 }
 
-// ListChannelVideos lists all videos from a channel
-func (s *Service) ListChannelVideos(channelID string, maxResults int64) ([]Video, error) {
+// NewServiceWithAPIKey creates a YouTube service that uses an API key instead
+// of OAuth2. It can read public data of any channel, but not the caller's own.
+func NewServiceWithAPIKey(apiKey string) (*Service, error) {
+	ctx := context.Background()
+	service, err := youtube.NewService(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("error creating YouTube client: %w", err)
+	}
+
+	return &Service{client: service, apiKeyMode: true}, nil
+}
+
+// ListChannelVideos lists all videos from a channel that pass the filter.
+// Cancelling ctx stops the listing between API calls.
+func (s *Service) ListChannelVideos(ctx context.Context, channelID string, maxResults int64, filter VideoFilter) ([]Video, error) {
 	// If no channel ID is provided, get the authenticated user's channel
 	if channelID == "" {
-		channelsCall := s.client.Channels.List([]string{"id"}).Mine(true)
-		channelResponse, err := channelsCall.Do()
+		mine, err := s.mineChannelID(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("error retrieving channel: %w", err)
-		}
-
-		if len(channelResponse.Items) == 0 {
-			return nil, fmt.Errorf("no channel found for authenticated user")
+			return nil, err
 		}
-
-		channelID = channelResponse.Items[0].Id
+		channelID = mine
 		fmt.Printf("Using channel ID: %s\n", channelID)
 	}
 
 	// Get uploads playlist ID
 	channelsCall := s.client.Channels.List([]string{"contentDetails"}).Id(channelID)
-	channelResponse, err := channelsCall.Do()
+	channelResponse, err := channelsCall.Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving channel details: %w", err)
 	}
@@ -64,57 +115,142 @@ func (s *Service) ListChannelVideos(channelID string, maxResults int64) ([]Video
 
 	uploadsPlaylistID := channelResponse.Items[0].ContentDetails.RelatedPlaylists.Uploads
 
-	// Get videos from uploads playlist
-	var videos []Video
-	nextPageToken := ""
+	return s.ListPlaylistVideos(ctx, uploadsPlaylistID, maxResults, filter)
+}
+
+// mineChannelID returns the channel ID of the authenticated user
+func (s *Service) mineChannelID(ctx context.Context) (string, error) {
+	if s.apiKeyMode {
+		return "", errOAuthRequired
+	}
 
-	for {
-		call := s.client.PlaylistItems.List([]string{"snippet"}).
-			PlaylistId(uploadsPlaylistID).
-			MaxResults(50)
+	channelResponse, err := s.client.Channels.List([]string{"id"}).Mine(true).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("error retrieving channel: %w", err)
+	}
 
-		if nextPageToken != "" {
-			call = call.PageToken(nextPageToken)
+	if len(channelResponse.Items) == 0 {
+		return "", fmt.Errorf("no channel found for authenticated user")
+	}
+
+	return channelResponse.Items[0].Id, nil
+}
+
+// enrichVideos fills in the full metadata of the given videos in place,
+// batching IDs 50 at a time into videos.list, and returns the set of IDs the
+// API returned. Videos the API does not return (e.g. deleted, or private to
+// another account) keep their basic fields.
+func (s *Service) enrichVideos(ctx context.Context, videos []Video) (map[string]bool, error) {
+	found := make(map[string]bool, len(videos))
+	index := make(map[string][]int, len(videos))
+	ids := make([]string, 0, len(videos))
+	for i, video := range videos {
+		if _, seen := index[video.ID]; !seen {
+			ids = append(ids, video.ID)
+		}
+		index[video.ID] = append(index[video.ID], i)
+	}
+
+	for start := 0; start < len(ids); start += constants.MaxVideosPerPage {
+		end := start + constants.MaxVideosPerPage
+		if end > len(ids) {
+			end = len(ids)
 		}
 
-		response, err := call.Do()
+		response, err := s.client.Videos.List(videoParts).Id(ids[start:end]...).Context(ctx).Do()
 		if err != nil {
-			return nil, fmt.Errorf("error retrieving playlist items: %w", err)
+			return nil, fmt.Errorf("error retrieving video details: %w", err)
 		}
 
 		for _, item := range response.Items {
-			videos = append(videos, Video{
-				ID:          item.Snippet.ResourceId.VideoId,
-				Title:       item.Snippet.Title,
-				Description: item.Snippet.Description,
-				PublishedAt: item.Snippet.PublishedAt,
-			})
-
-			if maxResults > 0 && int64(len(videos)) >= maxResults {
-				return videos, nil
+			found[item.Id] = true
+			for _, i := range index[item.Id] {
+				applyVideoDetails(&videos[i], item)
 			}
 		}
+	}
+
+	return found, nil
+}
+
+// GetVideos fetches full metadata for the given video IDs, preserving their
+// order. IDs the API does not return are omitted.
+func (s *Service) GetVideos(ctx context.Context, ids []string) ([]Video, error) {
+	videos := make([]Video, len(ids))
+	for i, id := range ids {
+		videos[i] = Video{ID: id}
+	}
+
+	found, err := s.enrichVideos(ctx, videos)
+	if err != nil {
+		return nil, err
+	}
+
+	result := videos[:0]
+	for _, video := range videos {
+		if found[video.ID] {
+			result = append(result, video)
+		}
+	}
+	return result, nil
+}
 
-		nextPageToken = response.NextPageToken
-		if nextPageToken == "" {
-			break
+// applyVideoDetails copies videos.list data into a Video
+func applyVideoDetails(video *Video, item *youtube.Video) {
+	if snippet := item.Snippet; snippet != nil {
+		video.Title = snippet.Title
+		video.Description = snippet.Description
+		video.PublishedAt = parseTime(snippet.PublishedAt)
+		video.ChannelID = snippet.ChannelId
+		video.ChannelTitle = snippet.ChannelTitle
+		video.Tags = snippet.Tags
+		video.CategoryID = snippet.CategoryId
+		video.DefaultLanguage = snippet.DefaultLanguage
+		video.LiveBroadcastContent = snippet.LiveBroadcastContent
+	}
+
+	if details := item.ContentDetails; details != nil {
+		if duration, err := parseISODuration(details.Duration); err == nil {
+			video.Duration = duration
 		}
+		video.Definition = details.Definition
+		video.HasCaptions = details.Caption == "true"
+	}
+
+	if status := item.Status; status != nil {
+		video.PrivacyStatus = status.PrivacyStatus
+		video.UploadStatus = status.UploadStatus
+	}
+
+	if stats := item.Statistics; stats != nil {
+		video.ViewCount = stats.ViewCount
+		video.LikeCount = stats.LikeCount
 	}
+}
 
-	return videos, nil
+// parseTime parses an API timestamp, returning the zero time if it is invalid
+func parseTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
 }
 
 // GetChannelInfo returns information about a channel
-func (s *Service) GetChannelInfo(channelID string) (*youtube.Channel, error) {
+func (s *Service) GetChannelInfo(ctx context.Context, channelID string) (*youtube.Channel, error) {
 	call := s.client.Channels.List([]string{"snippet", "contentDetails", "statistics"})
 	
 	if channelID == "" {
+		if s.apiKeyMode {
+			return nil, errOAuthRequired
+		}
 		call = call.Mine(true)
 	} else {
 		call = call.Id(channelID)
 	}
 
-	response, err := call.Do()
+	response, err := call.Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving channel info: %w", err)
 	}
@@ -126,3 +262,43 @@ func (s *Service) GetChannelInfo(channelID string) (*youtube.Channel, error) {
 	return response.Items[0], nil
 }
 
+// ListCaptions lists the caption tracks of a video.
+// This requires the youtube.force-ssl scope and ownership of the video.
+func (s *Service) ListCaptions(videoID string) ([]Caption, error) {
+	if s.apiKeyMode {
+		return nil, fmt.Errorf("captions cannot be downloaded with an API key: run 'yeetrap auth --feature captions' and drop --api-key")
+	}
+
+	response, err := s.client.Captions.List([]string{"snippet"}, videoID).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving captions: %w", err)
+	}
+
+	captions := make([]Caption, 0, len(response.Items))
+	for _, item := range response.Items {
+		captions = append(captions, Caption{
+			ID:        item.Id,
+			Language:  item.Snippet.Language,
+			Name:      item.Snippet.Name,
+			TrackKind: item.Snippet.TrackKind,
+		})
+	}
+
+	return captions, nil
+}
+
+// DownloadCaption downloads a caption track in the given format (e.g. "srt", "vtt")
+func (s *Service) DownloadCaption(captionID, format string) ([]byte, error) {
+	response, err := s.client.Captions.Download(captionID).Tfmt(format).Download()
+	if err != nil {
+		return nil, fmt.Errorf("error downloading caption: %w", err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading caption: %w", err)
+	}
+
+	return data, nil
+}
@@ -5,11 +5,15 @@ import (
 	"os"
 
 	"github.com/AlienFacepalm/YeeTrap/cmd"
+	"github.com/AlienFacepalm/YeeTrap/internal/interrupt"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if cmd.Interrupted() {
+			os.Exit(interrupt.ExitCode)
+		}
 		os.Exit(1)
 	}
 }